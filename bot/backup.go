@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	U "github.com/ashyaa/birtho/util"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DefaultBackupDir  = "backups"
+	DefaultBackupKeep = 10
+	// BackupCooldown is the minimum delay between two backups requested with the command.
+	BackupCooldown = time.Minute
+
+	backupPrefix     = "app-"
+	backupSuffix     = ".db"
+	backupTimeLayout = "20060102-150405"
+)
+
+// BackupOptions configures the scheduled database backups. Zero values fall back to the defaults,
+// backups are not scheduled without an interval.
+type BackupOptions struct {
+	Dir      string        `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Keep     int           `json:"keep,omitempty" yaml:"keep,omitempty"`       // number of snapshots kept
+	MaxAge   time.Duration `json:"max-age,omitempty" yaml:"max-age,omitempty"` // older snapshots are removed, except the latest one
+}
+
+func (o BackupOptions) dir() string {
+	if o.Dir == "" {
+		return DefaultBackupDir
+	}
+	return o.Dir
+}
+
+func (o BackupOptions) keep() int {
+	if o.Keep <= 0 {
+		return DefaultBackupKeep
+	}
+	return o.Keep
+}
+
+// WriteBackup writes a snapshot of the database in dir, in a read transaction so the snapshot is
+// consistent while the bot keeps writing. Returns the path of the snapshot.
+func WriteBackup(db *storm.DB, dir string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, backupPrefix+now.Format(backupTimeLayout)+backupSuffix)
+	tmp, err := os.CreateTemp(dir, "tmp-*"+backupSuffix)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	err = db.Bolt.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(tmp)
+		return err
+	})
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp.Name(), path)
+}
+
+// Backups returns the snapshots of dir from the newest to the oldest, with their time.
+func Backups(dir string) ([]string, []time.Time, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	paths := []string{}
+	times := make(map[string]time.Time)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+		t, err := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		paths = append(paths, path)
+		times[path] = t
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return times[paths[i]].After(times[paths[j]])
+	})
+	res := []time.Time{}
+	for _, path := range paths {
+		res = append(res, times[path])
+	}
+	return paths, res, nil
+}
+
+// PruneBackups removes the snapshots beyond the keep newest ones and the ones older than maxAge,
+// always keeping the newest snapshot. Returns the removed snapshots.
+func PruneBackups(dir string, keep int, maxAge time.Duration, now time.Time) ([]string, error) {
+	paths, times, err := Backups(dir)
+	if err != nil {
+		return nil, err
+	}
+	removed := []string{}
+	for i, path := range paths {
+		tooOld := maxAge > 0 && now.Sub(times[i]) > maxAge
+		if i == 0 || (i < keep && !tooOld) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// Backup writes a snapshot of the database and applies the retention rules.
+func (b *Bot) Backup() (string, error) {
+	b.backupMutex.Lock()
+	defer b.backupMutex.Unlock()
+	opts := b.conf.Backup
+	path, err := WriteBackup(b.db, opts.dir(), time.Now())
+	if err != nil {
+		return "", err
+	}
+	b.lastBackup = time.Now()
+	removed, err := PruneBackups(opts.dir(), opts.keep(), opts.MaxAge, time.Now())
+	if err != nil {
+		b.WarnE(err, "removing old backups")
+	}
+	b.Info("database saved to %s, %d old backups removed", path, len(removed))
+	return path, nil
+}
+
+// scheduleBackups backs the database up at the configured interval, until stopBackups is called.
+func (b *Bot) scheduleBackups() {
+	interval := b.conf.Backup.Interval
+	if interval <= 0 {
+		return
+	}
+	b.backupsStop = make(chan struct{})
+	b.backupsDone = make(chan struct{})
+	go func() {
+		defer close(b.backupsDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.backupsStop:
+				return
+			case <-ticker.C:
+				if _, err := b.Backup(); err != nil {
+					b.ErrorE(err, "backing up database")
+				}
+			}
+		}
+	}()
+	b.Info("database backups scheduled every %v in %s", interval, b.conf.Backup.dir())
+}
+
+func (b *Bot) stopBackups() {
+	if b.backupsStop == nil {
+		return
+	}
+	close(b.backupsStop)
+	<-b.backupsDone
+}
+
+// RestoreBackup replaces the database at dbPath with a snapshot, after checking the snapshot is a
+// valid database. The replaced database is kept beside it with a ".before-restore" suffix. The
+// bot must be stopped.
+func RestoreBackup(snapshot, dbPath string) error {
+	check, err := storm.Open(snapshot, storm.BoltOptions(0600, &bolt.Options{ReadOnly: true, Timeout: time.Second}))
+	if err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	var servers []Server
+	err = check.All(&servers)
+	check.Close()
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		// Fails if the bot is running
+		db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: time.Second})
+		if errors.Is(err, bolt.ErrTimeout) {
+			return fmt.Errorf("database %s is in use, stop the bot first", dbPath)
+		}
+		if err != nil {
+			return err
+		}
+		db.Close()
+		if err := copyFile(dbPath, dbPath+".before-restore"); err != nil {
+			return err
+		}
+	}
+	tmp := dbPath + ".restoring"
+	if err := copyFile(snapshot, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dbPath)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func BackupData(b *Bot, p CommandParameters) {
+	b.backupMutex.Lock()
+	last := b.lastBackup
+	b.backupMutex.Unlock()
+	if wait := BackupCooldown - time.Since(last); wait > 0 {
+		msg := fmt.Sprintf("A backup was made recently, try again %s.", U.Timestamp(time.Now().Add(wait)))
+		SendText(b.s, p.I, p.CID, msg)
+		return
+	}
+	path, err := b.Backup()
+	if err != nil {
+		p.Log.ErrorE(err, "backing up database")
+		SendText(b.s, p.I, p.CID, "Backup failed.")
+		return
+	}
+	SendText(b.s, p.I, p.CID, fmt.Sprintf("Database saved to `%s`.", filepath.Base(path)))
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupRestore(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	b.conf.Backup.Dir = t.TempDir()
+	for i := 0; i < 20; i++ {
+		b.NewServer(fmt.Sprintf("guild%d", i))
+	}
+
+	// Back up while servers are being written
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				serv := b.GetServer(fmt.Sprintf("guild%d", i%20))
+				serv.Users[fmt.Sprintf("user%d", i)] = []string{"m1i1"}
+				b.SaveServer(serv)
+			}
+		}
+	}()
+	path, err := b.Backup()
+	close(stop)
+	wg.Wait()
+	a.NoError(err)
+	a.Equal(b.conf.Backup.Dir, filepath.Dir(path))
+
+	// Restore over an existing database
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	db, err := storm.Open(dbPath)
+	a.NoError(err)
+	a.NoError(db.Save(&Server{ID: "lost"}))
+	a.ErrorContains(RestoreBackup(path, dbPath), "in use")
+	a.NoError(db.Close())
+
+	a.NoError(RestoreBackup(path, dbPath))
+	restored, err := storm.Open(dbPath)
+	a.NoError(err)
+	defer restored.Close()
+	var servers []Server
+	a.NoError(restored.All(&servers))
+	a.Len(servers, 20)
+	a.FileExists(dbPath + ".before-restore")
+
+	t.Run("invalid snapshot", func(t *testing.T) {
+		a := assert.New(t)
+		invalid := filepath.Join(t.TempDir(), "app-20231019-120000.db")
+		a.NoError(os.WriteFile(invalid, []byte("lorem ipsum"), 0600))
+		a.Error(RestoreBackup(invalid, filepath.Join(t.TempDir(), "app.db")))
+	})
+}
+
+func TestPruneBackups(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	now := time.Date(2023, 10, 19, 12, 0, 0, 0, time.Local)
+	for _, days := range []int{0, 1, 2, 3, 10, 40} {
+		name := backupPrefix + now.AddDate(0, 0, -days).Format(backupTimeLayout) + backupSuffix
+		a.NoError(os.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+	a.NoError(os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0600))
+
+	removed, err := PruneBackups(dir, 5, 30*24*time.Hour, now)
+	a.NoError(err)
+	a.Len(removed, 1) // older than 30 days
+	removed, err = PruneBackups(dir, 3, 0, now)
+	a.NoError(err)
+	a.Len(removed, 2) // beyond the 3 newest
+	paths, times, err := Backups(dir)
+	a.NoError(err)
+	a.Len(paths, 3)
+	a.Equal(now, times[0])
+
+	// The newest snapshot is kept whatever its age
+	removed, err = PruneBackups(dir, 3, time.Hour, now.AddDate(1, 0, 0))
+	a.NoError(err)
+	a.Len(removed, 2)
+	a.FileExists(paths[0])
+}
+
+func TestBackupCommand(t *testing.T) {
+	const owner = "400000000000000001"
+	a := assert.New(t)
+	s := newFakeSession(0)
+	b := newTestBot(t, s)
+	b.conf.Backup.Dir = t.TempDir()
+	b.conf.Owners = []string{owner}
+	b.NewServer("guild")
+
+	// Server admins cannot back up the databases of all the servers
+	HandlerFromMessageCreate(b, b.command("backup"))(nil, messageCreate("guild", "channel", "admin", "b!backup"))
+	paths, _, err := Backups(b.conf.Backup.Dir)
+	a.NoError(err)
+	a.Empty(paths)
+
+	HandlerFromMessageCreate(b, b.command("backup"))(nil, messageCreate("guild", "channel", owner, "b!backup"))
+	paths, _, err = Backups(b.conf.Backup.Dir)
+	a.NoError(err)
+	a.Len(paths, 1)
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+)
+
+// Visibilities of the banned players in the leaderboards
+const (
+	BanShow = "show"
+	BanHide = "hide"
+)
+
+var banVisibilities = []string{BanShow, BanHide}
+
+// Ban excludes a player from the game of a server.
+type Ban struct {
+	By     string // admin who banned the player
+	Reason string
+	Hidden bool // hidden from the leaderboards
+	Time   time.Time
+}
+
+// ban returns the ban of the player, kept when they erased their data.
+func (s Server) ban(uid string) (Ban, bool) {
+	if ban, ok := s.Banned[uid]; ok {
+		return ban, true
+	}
+	if len(s.ForgottenBans) == 0 {
+		return Ban{}, false
+	}
+	ban, ok := s.ForgottenBans[userHash(uid)]
+	return ban, ok
+}
+
+// Excluded returns true if the grabs of the player do not count, because they were banned or left
+// the game.
+func (s Server) Excluded(uid string) bool {
+	_, banned := s.ban(uid)
+	return banned || U.Contains(s.OptedOut, uid)
+}
+
+// Hidden returns true if the player does not appear in the leaderboards.
+func (s Server) Hidden(uid string) bool {
+	ban, _ := s.ban(uid)
+	return ban.Hidden || U.Contains(s.OptedOut, uid) || IsPseudonym(uid)
+}
+
+// visible returns the leaderboard without the hidden players, ranked again.
+func (s Server) visible(lb Leaderboard, ascending bool) Leaderboard {
+	res := Leaderboard{}
+	for _, sb := range lb {
+		if !s.Hidden(sb.UID) {
+			res = append(res, sb)
+		}
+	}
+	if len(res) != len(lb) {
+		res.sortBy(ascending)
+	}
+	return res
+}
+
+func BanUser(b *Bot, p CommandParameters) {
+	uid := p.Options["user"].(string)
+	visibility, _ := p.Options["leaderboards"].(string)
+	reason, _ := p.Options["reason"].(string)
+	if p.S.Banned == nil {
+		p.S.Banned = make(map[string]Ban)
+	}
+	p.S.Banned[uid] = Ban{By: p.UID, Reason: reason, Hidden: visibility == BanHide, Time: time.Now()}
+	b.SaveServer(p.S)
+	b.reviewFlags(p.GID, uid, p.Log)
+
+	msg := fmt.Sprintf("Banned %s from the game: their grabs will not count anymore.", U.BuildUserTag(uid))
+	if visibility == BanHide {
+		msg += " They are hidden from the leaderboards."
+	}
+	SendText(b.s, p.I, p.CID, msg)
+}
+
+func UnbanUser(b *Bot, p CommandParameters) {
+	uid := p.Options["user"].(string)
+	if _, ok := p.S.ban(uid); !ok {
+		SendText(b.s, p.I, p.CID, fmt.Sprintf("%s is not banned.", U.BuildUserTag(uid)))
+		return
+	}
+	delete(p.S.Banned, uid)
+	delete(p.S.ForgottenBans, userHash(uid))
+	b.SaveServer(p.S)
+	SendText(b.s, p.I, p.CID, fmt.Sprintf("%s can play again!", U.BuildUserTag(uid)))
+}
+
+func OptOut(b *Bot, p CommandParameters) {
+	if U.Contains(p.S.OptedOut, p.UID) {
+		SendText(b.s, p.I, p.CID, fmt.Sprintf("You already left the game. Use `%soptin` to play again.", p.S.Prefix))
+		return
+	}
+	p.S = b.RemovePlayer(p.S, p.UID)
+	p.S.OptedOut = append(p.S.OptedOut, p.UID)
+	b.SaveServer(p.S)
+
+	msg := fmt.Sprintf("%s left the game: their items and score were deleted, and visitors will ignore them. Use `%soptin` to play again.",
+		U.BuildUserTag(p.UID), p.S.Prefix)
+	SendText(b.s, p.I, p.CID, msg)
+}
+
+func OptIn(b *Bot, p CommandParameters) {
+	if !U.Contains(p.S.OptedOut, p.UID) {
+		SendText(b.s, p.I, p.CID, "You are already playing!")
+		return
+	}
+	p.S.OptedOut = U.Remove(p.S.OptedOut, p.UID)
+	b.SaveServer(p.S)
+	SendText(b.s, p.I, p.CID, fmt.Sprintf("Welcome back %s!", U.BuildUserTag(p.UID)))
+}
@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBans(t *testing.T) {
+	const (
+		channel = "300000000000000001"
+		alice   = "400000000000000001"
+		bob     = "400000000000000002"
+		carol   = "400000000000000003"
+	)
+	a := assert.New(t)
+	s := newFakeSession(0)
+	b := newTestBot(t, s)
+	serv := b.NewServer("guild")
+	serv.G.On = true
+	serv.Channels = []string{channel}
+	serv.Admins = []string{"admin"}
+	serv.Users = map[string][]string{alice: {"m1i3"}, bob: {"m1i2"}, carol: {"m1i1"}}
+	serv.Global = true
+	b.SaveServer(b.RecomputeLeaderboard(serv))
+	command := func(uid, name, content string) {
+		HandlerFromMessageCreate(b, b.command(name))(nil, messageCreate("guild", "channel", uid, content))
+	}
+	ranked := func(window string) map[string]string {
+		res := map[string]string{}
+		for _, sb := range b.leaderboardMenu(b.GetServer("guild"), "channel", window, RankingPoints).L {
+			res[sb.UID] = sb.Rank
+		}
+		return res
+	}
+
+	t.Run("ban", func(t *testing.T) {
+		command("admin", "ban", "b!ban <@"+alice+"> show farming alts")
+		a.Equal("farming alts", b.GetServer("guild").Banned[alice].Reason)
+		a.True(b.GetServer("guild").Excluded(alice))
+		a.False(b.GetServer("guild").Hidden(alice))
+		a.Equal(map[string]string{alice: "1st", bob: "2nd", carol: "3rd"}, ranked(WindowAll))
+		grabAfter(b, "guild", channel, alice, time.Second)
+		a.Equal([]string{"m1i3"}, b.GetServer("guild").Users[alice])
+
+		command("admin", "ban", "b!ban <@"+alice+"> hide")
+		a.True(b.GetServer("guild").Hidden(alice))
+		a.Equal(map[string]string{bob: "1st", carol: "2nd"}, ranked(WindowAll))
+		lb, _ := b.GlobalLeaderboard()
+		a.Len(lb, 2)
+		grabAfter(b, "guild", channel, bob, time.Second)
+		a.NotContains(ranked(WindowToday), alice)
+		a.Contains(ranked(WindowToday), bob)
+
+		command("admin", "unban", "b!unban <@"+alice+">")
+		a.False(b.GetServer("guild").Excluded(alice))
+		a.Equal("1st", ranked(WindowAll)[alice])
+	})
+
+	t.Run("opt-out", func(t *testing.T) {
+		command(carol, "optout", "b!optout")
+		serv := b.GetServer("guild")
+		a.NotContains(serv.Users, carol)
+		a.NotContains(ranked(WindowAll), carol)
+		a.True(serv.Excluded(carol))
+		grabAfter(b, "guild", channel, carol, time.Second)
+		a.NotContains(b.GetServer("guild").Users, carol)
+		a.NotContains(ranked(WindowAll), carol)
+		command(carol, "score", "b!score")
+		serv = b.GetServer("guild")
+		a.NotContains(serv.Users, carol)
+		for _, sb := range serv.Lb {
+			a.NotEqual(carol, sb.UID)
+		}
+
+		command(carol, "optin", "b!optin")
+		a.False(b.GetServer("guild").Excluded(carol))
+		grabAfter(b, "guild", channel, carol, time.Second)
+		a.Len(b.GetServer("guild").Users[carol], 1)
+	})
+}
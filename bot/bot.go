@@ -1,62 +1,128 @@
 package bot
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"sort"
 	"strconv"
+	"sync"
+	"time"
 
+	"github.com/asdine/storm/v3"
 	U "github.com/ashyaa/birtho/util"
 	DG "github.com/bwmarrin/discordgo"
 	LR "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
 )
 
-func New(log *LR.Logger) (*Bot, error) {
-	conf, err := ReadConfig(log)
-	if err != nil {
-		log.Error("error reading config: ", err)
-		return nil, err
+// newBot returns a bot with the game data of the configuration, without database nor session.
+func newBot(log *LR.Logger, conf Config) *Bot {
+	seed := conf.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
 	}
-
-	res := Bot{
+	res := &Bot{
 		Log:                 log,
 		Items:               make(map[string]Item),
 		InteractionHandlers: make(InteractionHandlers),
+		guildLocks:          make(map[string]*sync.Mutex),
+		ranks:               make(map[string]*rankIndex),
+		members:             newMemberCache(),
+		thumbnails:          newThumbnailCache(httpThumbnail),
 		Commands:            make([]Command, 0),
-		rng:                 U.NewRNG(),
+		rng:                 U.NewSeededRNG(seed),
+		seed:                seed,
+		conf:                conf,
+		confLoaded:          time.Now(),
 	}
 	res.buildGameData(conf)
+	return res
+}
+
+func New(log *LR.Logger, conf Config) (*Bot, error) {
+	var err error
+	res := newBot(log, conf)
+	res.Info("game rolls seeded with %d", res.seed) // set it as seed in the configuration to replay them
+	for _, m := range res.Monsters {
+		res.thumbnails.Get(m.URL) // start downloading the images of rendered boards
+	}
 
-	res.s, err = DG.New("Bot " + conf.Token)
+	res.ws, err = DG.New("Bot " + conf.Token)
 	if err != nil {
 		log.Error("error creating session: ", err)
 		return nil, err
 	}
+	res.s = res.ws
 
 	// Open the database
 	res.OpenDB()
+	res.scheduleBackups()
+	res.pruneGrabs()
 
-	res.s.Identify.Intents = DG.IntentsGuildMessages | DG.IntentGuildMessageReactions | DG.IntentGuildMembers
+	// Message content and guild members are privileged intents, enabled in the Discord developer
+	// portal of the application
+	res.ws.Identify.Intents = DG.IntentsGuildMessages | DG.IntentMessageContent | DG.IntentGuildMessageReactions | DG.IntentGuildMembers
+	res.watchMembers(res.ws)
 
 	// Open a websocket connection to Discord and begin listening.
-	err = res.s.Open()
+	err = res.ws.Open()
 	if err != nil {
 		res.ErrorE(err, "error opening connection")
 		return nil, err
 	}
-	res.UserID = res.s.State.User.ID
-	res.Menus = make(map[string]Menu)
+	res.UserID = res.ws.State.User.ID
+	res.Menus = NewMenuManager(res.s, res.WithFields(LR.Fields{"component": "menus"}), MenuExpiryInterval)
 	res.Mention = U.BuildUserTag(res.UserID)
 
 	// Install command handlers
 	res.SetupCommands()
 
-	return &res, nil
+	return res, nil
+}
+
+// Open returns a bot using the database at path without connecting to Discord, for offline
+// commands, creating the database unless readOnly is set. The database cannot be opened while the
+// bot is running.
+func Open(log *LR.Logger, conf Config, path string, readOnly bool) (*Bot, error) {
+	if _, err := os.Stat(path); readOnly && err != nil {
+		return nil, err
+	}
+	res := newBot(log, conf)
+	var err error
+	res.db, err = storm.Open(path, storm.BoltOptions(0600, &bolt.Options{
+		ReadOnly: readOnly,
+		Timeout:  time.Second,
+	}))
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("database %s is in use, stop the bot first", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !readOnly {
+		if err = res.migrate(); err != nil {
+			res.db.Close()
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Close closes the database of a bot returned by Open.
+func (b *Bot) Close() error {
+	return b.db.Close()
 }
 
 func (b *Bot) SetupCommands() {
 	b.Commands = append(b.Commands, commandList...)
 	buildOptions(b)
 	b.buildInteractionHandlers()
-	b.s.AddHandler(func(s *DG.Session, i *DG.InteractionCreate) {
+	b.ws.AddHandler(func(s *DG.Session, i *DG.InteractionCreate) {
+		if !b.enter() {
+			return
+		}
+		defer b.leave()
 		switch i.Type {
 		case DG.InteractionMessageComponent:
 			PageReact(b)(s, i)
@@ -68,36 +134,28 @@ func (b *Bot) SetupCommands() {
 			b.Warn("interaction of type  %v is currently not supported", i.Type)
 		}
 	})
-	dgCmds, err := b.s.ApplicationCommands(b.UserID, "")
-	if err != nil {
-		dgCmds = make([]*DG.ApplicationCommand, 0)
-	}
-	existingCommands := []string{}
-	for _, c := range dgCmds {
-		existingCommands = U.AppendUnique(existingCommands, c.Name)
-	}
 	for _, cmd := range b.Commands {
-		b.s.AddHandler(HandlerFromMessageCreate(b, cmd))
-		if cmd.appCmd != nil && !U.Contains(existingCommands, cmd.Name) {
-			cmd.appCmd.Name = cmd.Name
-			_, err := b.s.ApplicationCommandCreate(b.UserID, "", cmd.appCmd)
-			if err != nil {
-				b.Fatal("cannot create '%s' command: %v", cmd.Name, err)
-			}
-		}
+		b.ws.AddHandler(HandlerFromMessageCreate(b, cmd))
 		b.Info("command \"%s\" setup", cmd.Name)
 	}
+	// Overwrite the registered commands, so that changed definitions reach deployed bots. Discord
+	// leaves the unchanged commands as they are.
+	if _, err := b.ws.ApplicationCommandBulkOverwrite(b.UserID, "", b.applicationCommands()); err != nil {
+		b.Fatal("cannot register application commands: %v", err)
+	}
 	b.Info("all commands have been setup")
 }
 
-func (b *Bot) Stop() {
-	b.Info("closing database")
-	err := b.db.Close()
-	if err != nil {
-		b.ErrorE(err, "closing database")
+// applicationCommands returns the definitions of the application commands.
+func (b *Bot) applicationCommands() []*DG.ApplicationCommand {
+	res := []*DG.ApplicationCommand{}
+	for _, cmd := range b.Commands {
+		if cmd.appCmd != nil {
+			cmd.appCmd.Name = cmd.Name
+			res = append(res, cmd.appCmd)
+		}
 	}
-	b.s.Close()
-	b.Info("gracefully shutting down")
+	return res
 }
 
 func (b *Bot) buildGameData(conf Config) {
@@ -131,6 +189,7 @@ func (b *Bot) buildGameData(conf Config) {
 	if len(b.Monsters) == 0 {
 		b.Fatal("no valid monsters in the configuration")
 	}
+	b.pack = packHash(b.Items)
 }
 
 func (b *Bot) AddItems(items []Item) {
@@ -1,7 +1,10 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	U "github.com/ashyaa/birtho/util"
@@ -17,6 +20,11 @@ func RemoveChannel(b *Bot, p CommandParameters) {
 	}
 
 	p.S.Channels = U.Remove(p.S.Channels, targetChannel)
+	delete(p.S.G.ChannelStates, targetChannel)
+	if spawn, ok := p.S.G.Monsters[targetChannel]; ok {
+		b.vanish(context.Background(), targetChannel, spawn)
+		delete(p.S.G.Monsters, targetChannel)
+	}
 	b.SaveServer(p.S)
 
 	msg := fmt.Sprintf("Removed channel %s from the list of spawn channels!", tag)
@@ -54,3 +62,86 @@ func Channels(b *Bot, p CommandParameters) {
 	msg := fmt.Sprintf("List of spawn channels: %s", strings.Join(channelTags, ", "))
 	SendText(b.s, p.I, p.CID, msg)
 }
+
+// parsePool parses comma-separated monster IDs or names, each with an optional weight after a
+// colon (eg "1:2,witch"). "all" or an empty list give an empty pool, where all monsters can come.
+func (b *Bot) parsePool(spec string) (map[string]float64, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "all") {
+		return nil, nil
+	}
+	res := make(map[string]float64)
+	for _, part := range strings.Split(spec, ",") {
+		name, weightText, hasWeight := strings.Cut(strings.TrimSpace(part), ":")
+		weight := 1.0
+		if hasWeight {
+			var err error
+			weight, err = strconv.ParseFloat(weightText, 64)
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid weight `%s` for `%s`", weightText, name)
+			}
+		}
+		id, ok := b.monsterID(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown monster `%s`", name)
+		}
+		res[id] = weight
+	}
+	return res, nil
+}
+
+// monsterID returns the ID of the monster of the given ID or name.
+func (b *Bot) monsterID(name string) (string, bool) {
+	if _, ok := b.Monsters[name]; ok {
+		return name, true
+	}
+	for id, m := range b.Monsters {
+		if strings.EqualFold(m.Name, name) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// describePool returns the monsters of a pool with their weights.
+func (b *Bot) describePool(pool map[string]float64) string {
+	names := []string{}
+	for id, weight := range pool {
+		m, ok := b.Monsters[id]
+		if !ok {
+			continue
+		}
+		if weight == 1 {
+			names = append(names, m.Name)
+		} else {
+			names = append(names, fmt.Sprintf("%s ×%g", m.Name, weight))
+		}
+	}
+	if len(names) == 0 {
+		return "all monsters"
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func SetPool(b *Bot, p CommandParameters) {
+	targetChannel := p.Options["channel"].(string)
+	tag := U.BuildChannelTag(targetChannel)
+	if !U.Contains(p.S.Channels, targetChannel) {
+		msg := fmt.Sprintf("Channel %s is not a spawn channel", tag)
+		SendText(b.s, p.I, p.CID, msg)
+		return
+	}
+	spec, _ := p.Options["monsters"].(string)
+	pool, err := b.parsePool(spec)
+	if err != nil {
+		SendText(b.s, p.I, p.CID, err.Error())
+		return
+	}
+
+	p.S.G.Channel(targetChannel).Pool = pool
+	b.SaveServer(p.S)
+
+	msg := fmt.Sprintf("Monsters coming to %s: %s", tag, b.describePool(pool))
+	SendText(b.s, p.I, p.CID, msg)
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelStates(t *testing.T) {
+	const (
+		busy      = "200000000000000001"
+		quiet     = "200000000000000002"
+		elsewhere = "200000000000000003"
+	)
+	a := assert.New(t)
+	s := newFakeSession(0)
+	s.channels = []string{busy, quiet}
+	b := newTestBot(t, s)
+	serv := b.NewServer("guild")
+	serv.G.On = true
+	serv.Channels = []string{busy, quiet}
+	serv.Admins = []string{"admin"}
+	b.SaveServer(serv)
+
+	t.Run("cooldowns", func(t *testing.T) {
+		now := time.Now()
+		serv.cooldownAt(b.rng, busy, now)
+		a.False(serv.canSpawnAt(busy, now))
+		a.True(serv.canSpawnAt(quiet, now))
+		serv.Cooldown(b.rng)
+		a.False(serv.canSpawnAt(quiet, now))
+	})
+
+	t.Run("histories", func(t *testing.T) {
+		handler := HandlerFromMessageCreate(b, b.command("spawn"))
+		handler(nil, messageCreate("guild", busy, "alice", "hello"))
+		handler(nil, messageCreate("guild", busy, "bob", "hi"))
+		states := b.GetServer("guild").G.ChannelStates
+		a.Equal("bob", states[busy].LastMessages[HistoryDepth-1].Author)
+		a.Equal("alice", states[busy].LastMessages[HistoryDepth-2].Author)
+		a.NotContains(states, quiet)
+	})
+
+	t.Run("pools", func(t *testing.T) {
+		pool, err := b.parsePool("ghost:2")
+		a.NoError(err)
+		a.Equal(map[string]float64{"1": 2}, pool)
+		pool, err = b.parsePool("all")
+		a.NoError(err)
+		a.Nil(pool)
+		for _, invalid := range []string{"witch", "1:0", "1:lorem"} {
+			_, err = b.parsePool(invalid)
+			a.Error(err, invalid)
+		}
+
+		witch := Monster{ID: 2, Name: "Witch"}
+		b.Monsters["2"] = witch
+		defer delete(b.Monsters, "2")
+		counts := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			counts[b.randomMonsterIn(b.rng, map[string]float64{"1": 1, "2": 3}).Name]++
+		}
+		a.InDelta(750, counts["Witch"], 60)
+		a.Equal("Witch", b.randomMonsterIn(b.rng, map[string]float64{"2": 1}).Name)
+		a.Equal("Ghost", b.randomMonsterIn(b.rng, map[string]float64{"404": 1}).Name)
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		HandlerFromMessageCreate(b, b.command("setpool"))(nil, messageCreate("guild", "channel", "admin", "b!setpool "+U.BuildChannelTag(quiet)+" 1:2"))
+		a.Equal(map[string]float64{"1": 2}, b.GetServer("guild").G.ChannelStates[quiet].Pool)
+		HandlerFromMessageCreate(b, b.command("setpool"))(nil, messageCreate("guild", "channel", "admin", "b!setpool "+U.BuildChannelTag(elsewhere)+" 1"))
+		a.NotContains(b.GetServer("guild").G.ChannelStates, elsewhere)
+
+		HandlerFromMessageCreate(b, b.command("info"))(nil, messageCreate("guild", "channel", "admin", "b!info"))
+		info := []string{}
+		for _, msg := range s.sent {
+			for _, embed := range msg.Embeds {
+				for _, field := range embed.Fields {
+					if field.Name == "Channel" {
+						info = append(info, field.Value)
+					}
+				}
+			}
+		}
+		a.Len(info, 2)
+		a.Contains(info[0], U.BuildChannelTag(busy))
+		a.Contains(info[0], "Monsters: all monsters")
+		a.Contains(info[1], "Monsters: Ghost ×2")
+
+		// A visitor of a removed channel vanishes
+		serv := b.GetServer("guild")
+		spawn := MonsterSpawn{ID: "1", Message: snowflake()}
+		serv.G.Monsters[quiet] = spawn
+		b.SaveServer(serv)
+		HandlerFromMessageCreate(b, b.command("rmvchan"))(nil, messageCreate("guild", "channel", "admin", "b!rmvchan "+U.BuildChannelTag(quiet)))
+		serv = b.GetServer("guild")
+		a.NotContains(serv.Channels, quiet)
+		a.NotContains(serv.G.Monsters, quiet)
+		a.Equal("The visitor vanished.", s.sent[spawn.Message].Embeds[0].Title)
+	})
+}
@@ -0,0 +1,308 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	U "github.com/ashyaa/birtho/util"
+)
+
+// CheatOptions flag the players grabbing visitors too fast or too regularly to be human, as
+// macros and auto-typers do. The zero value flags nobody.
+type CheatOptions struct {
+	MinReaction time.Duration `json:"min-reaction,omitempty" yaml:"min-reaction,omitempty"` // flag grabs faster than this
+	// Window is the number of last grabs of a player whose reaction times are checked for
+	// regularity
+	Window int `json:"window,omitempty" yaml:"window,omitempty"`
+	// MinSpread flags the players whose reaction times over the window deviate from their mean by
+	// less than this fraction of it
+	MinSpread float64 `json:"min-spread,omitempty" yaml:"min-spread,omitempty"`
+}
+
+// Anti-cheat rules
+const (
+	RuleFast    = "fast"
+	RuleRegular = "regular"
+)
+
+const (
+	// maxListedFlags is the most flags listed by the flags command.
+	maxListedFlags = 20
+	// reviewedGrabs is the number of last grabs of a player summed up by the flags command.
+	reviewedGrabs = 100
+)
+
+// CheatFlag records a player suspected of cheating, until an admin reviews it.
+type CheatFlag struct {
+	ID         int    `storm:"id,increment"`
+	Guild      string `storm:"index"`
+	UID        string `storm:"index"`
+	Rule       string
+	Detail     string
+	Time       time.Time
+	Reviewed   bool
+	ReviewedAt time.Time
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
+
+// lastReactions returns the known reaction times of the last grabs of the player made after since,
+// latest first. The grabs of the player are read from the index by batches, until n reactions are
+// found.
+func (b *Bot) lastReactions(gid, uid string, n int, since time.Time, log Logger) []time.Duration {
+	res := []time.Duration{}
+	for skip := 0; len(res) < n; skip += n {
+		var events []GrabEvent
+		err := b.db.Find("UID", uid, &events, storm.Reverse(), storm.Skip(skip), storm.Limit(n))
+		if err != nil {
+			if !errors.Is(err, storm.ErrNotFound) {
+				log.ErrorE(err, "listing grabs of %s", uid)
+			}
+			break
+		}
+		for _, event := range events {
+			if !event.Time.After(since) {
+				return res
+			}
+			if event.Guild == gid && event.Reaction > 0 && len(res) < n {
+				res = append(res, event.Reaction)
+			}
+		}
+		if len(events) < n {
+			break
+		}
+	}
+	return res
+}
+
+// playerFlags returns the flags of the player in the server, latest first.
+func (b *Bot) playerFlags(gid, uid string) ([]CheatFlag, error) {
+	var flags []CheatFlag
+	err := b.db.Find("UID", uid, &flags, storm.Reverse())
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		return nil, err
+	}
+	res := []CheatFlag{}
+	for _, f := range flags {
+		if f.Guild == gid {
+			res = append(res, f)
+		}
+	}
+	return res, nil
+}
+
+// lastReview returns when a flag of the rule was last reviewed for the player, or the zero time.
+func (b *Bot) lastReview(gid, uid, rule string, log Logger) time.Time {
+	flags, err := b.playerFlags(gid, uid)
+	if err != nil {
+		log.ErrorE(err, "listing flags of %s", uid)
+	}
+	res := time.Time{}
+	for _, f := range flags {
+		if f.Rule == rule && f.ReviewedAt.After(res) {
+			res = f.ReviewedAt
+		}
+	}
+	return res
+}
+
+// meanDeviation returns the mean and the standard deviation of the durations.
+func meanDeviation(durations []time.Duration) (time.Duration, time.Duration) {
+	if len(durations) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, d := range durations {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(durations))
+	var squares float64
+	for _, d := range durations {
+		squares += (float64(d) - mean) * (float64(d) - mean)
+	}
+	return time.Duration(mean), time.Duration(math.Sqrt(squares / float64(len(durations))))
+}
+
+// checkGrab flags the player of the grab if its reaction time is implausible, alone or with the
+// previous ones. Once a regularity flag is reviewed, only the grabs made after the review count,
+// so that a dismissed flag is not raised again by the same grabs.
+func (b *Bot) checkGrab(serv Server, event GrabEvent, log Logger) {
+	o := b.conf.AntiCheat
+	if event.Reaction == 0 {
+		return
+	}
+	if o.MinReaction > 0 && event.Reaction < o.MinReaction {
+		b.flag(serv, event.UID, RuleFast, fmt.Sprintf("grabbed %s after the visitor came", seconds(event.Reaction)), log)
+	}
+	if o.Window > 1 && o.MinSpread > 0 {
+		since := b.lastReview(serv.ID, event.UID, RuleRegular, log)
+		reactions := b.lastReactions(serv.ID, event.UID, o.Window, since, log)
+		if len(reactions) < o.Window {
+			return
+		}
+		mean, deviation := meanDeviation(reactions)
+		if float64(deviation) < o.MinSpread*float64(mean) {
+			b.flag(serv, event.UID, RuleRegular, fmt.Sprintf("last %d grabs after %s ± %s",
+				len(reactions), seconds(mean), seconds(deviation)), log)
+		}
+	}
+}
+
+// flag records the player as suspect and warns the admins, unless the player has a flag of the
+// same rule pending review.
+func (b *Bot) flag(serv Server, uid, rule, detail string, log Logger) {
+	flags, err := b.playerFlags(serv.ID, uid)
+	if err != nil {
+		log.ErrorE(err, "listing flags of %s", uid)
+		return
+	}
+	for _, f := range flags {
+		if f.Rule == rule && !f.Reviewed {
+			return
+		}
+	}
+	f := CheatFlag{Guild: serv.ID, UID: uid, Rule: rule, Detail: detail, Time: time.Now()}
+	if err := b.db.Save(&f); err != nil {
+		log.ErrorE(err, "flagging %s", uid)
+		return
+	}
+	log.Warn("flagged %s: %s", uid, detail)
+	if serv.AdminChannel == "" {
+		return
+	}
+	msg := fmt.Sprintf("🚩 %s flagged as `%s`: %s. Review with `%sflags`.", U.BuildUserTag(uid), rule, detail, serv.Prefix)
+	if _, err := b.s.ChannelMessageSend(serv.AdminChannel, msg); err != nil {
+		log.ErrorE(err, "posting flag of %s", uid)
+	}
+}
+
+// Flags returns the flags of the server, only those pending review if uid is empty, or all those
+// of the player otherwise. Latest first.
+func (b *Bot) Flags(gid, uid string) []CheatFlag {
+	if uid != "" {
+		res, err := b.playerFlags(gid, uid)
+		if err != nil {
+			b.ErrorE(err, "listing flags of %s in server %s", uid, gid)
+		}
+		return res
+	}
+	var flags []CheatFlag
+	err := b.db.Find("Guild", gid, &flags, storm.Reverse())
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		b.ErrorE(err, "listing flags of server %s", gid)
+	}
+	res := []CheatFlag{}
+	for _, f := range flags {
+		if !f.Reviewed {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// reviewFlags marks the pending flags of the player as reviewed. Returns the number of flags
+// reviewed.
+func (b *Bot) reviewFlags(gid, uid string, log Logger) int {
+	flags, err := b.playerFlags(gid, uid)
+	if err != nil {
+		log.ErrorE(err, "listing flags of %s", uid)
+	}
+	count := 0
+	for _, f := range flags {
+		if f.Reviewed {
+			continue
+		}
+		f.Reviewed = true
+		f.ReviewedAt = time.Now()
+		if err := b.db.Update(&f); err != nil {
+			log.ErrorE(err, "reviewing flag %d", f.ID)
+		}
+		count++
+	}
+	return count
+}
+
+func SetAdminChannel(b *Bot, p CommandParameters) {
+	targetChannel, ok := p.Options["channel"].(string)
+	if !ok {
+		p.S.AdminChannel = ""
+		b.SaveServer(p.S)
+		SendText(b.s, p.I, p.CID, "Flags will not be posted anymore.")
+		return
+	}
+	tag := U.BuildChannelTag(targetChannel)
+	if !U.IsValidChannel(b.s, p.GID, targetChannel) {
+		msg := fmt.Sprintf("Channel `%s` is not a valid channel", tag)
+		SendText(b.s, p.I, p.CID, msg)
+		return
+	}
+
+	p.S.AdminChannel = targetChannel
+	b.SaveServer(p.S)
+
+	msg := fmt.Sprintf("Players suspected of cheating will be flagged in %s!", tag)
+	SendText(b.s, p.I, p.CID, msg)
+}
+
+func ShowFlags(b *Bot, p CommandParameters) {
+	uid, _ := p.Options["user"].(string)
+	flags := b.Flags(p.GID, uid)
+	if len(flags) == 0 {
+		SendText(b.s, p.I, p.CID, "No flags to review.")
+		return
+	}
+
+	lines := []string{}
+	if uid != "" {
+		reactions := b.lastReactions(p.GID, uid, reviewedGrabs, time.Time{}, p.Log)
+		mean, deviation := meanDeviation(reactions)
+		lines = append(lines, fmt.Sprintf("Flags of %s, reacting after %s ± %s over their last %d grabs:",
+			U.BuildUserTag(uid), seconds(mean), seconds(deviation), len(reactions)))
+	} else {
+		lines = append(lines, "Flags pending review:")
+	}
+	for i, f := range flags {
+		if i == maxListedFlags {
+			lines = append(lines, fmt.Sprintf("… and %d more", len(flags)-maxListedFlags))
+			break
+		}
+		status := ""
+		if f.Reviewed {
+			status = " (reviewed)"
+		}
+		lines = append(lines, fmt.Sprintf("• %s %s `%s`: %s%s", U.Timestamp(f.Time), U.BuildUserTag(f.UID), f.Rule, f.Detail, status))
+	}
+	lines = append(lines, fmt.Sprintf("Use `%sdismiss`, `%sban` or `%srevoke` once reviewed.", p.S.Prefix, p.S.Prefix, p.S.Prefix))
+	SendText(b.s, p.I, p.CID, strings.Join(lines, "\n"))
+}
+
+func DismissFlags(b *Bot, p CommandParameters) {
+	uid := p.Options["user"].(string)
+	n := b.reviewFlags(p.GID, uid, p.Log)
+	SendText(b.s, p.I, p.CID, fmt.Sprintf("Dismissed %d flags of %s.", n, U.BuildUserTag(uid)))
+}
+
+func RevokeItems(b *Bot, p CommandParameters) {
+	uid := p.Options["user"].(string)
+	itemID, ok := p.Options["item"].(string)
+	if !ok {
+		n := len(p.S.Users[uid])
+		b.SaveServer(b.RemovePlayer(p.S, uid))
+		SendText(b.s, p.I, p.CID, fmt.Sprintf("Revoked the %d items of %s.", n, U.BuildUserTag(uid)))
+		return
+	}
+
+	serv, err := b.RemoveItem(p.S, uid, itemID)
+	if err != nil {
+		SendText(b.s, p.I, p.CID, err.Error())
+		return
+	}
+	b.SaveServer(serv)
+	SendText(b.s, p.I, p.CID, fmt.Sprintf("Revoked item `%s` of %s.", itemID, U.BuildUserTag(uid)))
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// grabAfter makes a visitor come in the channel, and the player grab it after the reaction time.
+func grabAfter(b *Bot, gid, cid, uid string, reaction time.Duration) {
+	serv := b.GetServer(gid)
+	serv.G.Monsters[cid] = MonsterSpawn{ID: "1", Message: snowflakeAt(time.Now().Add(-reaction)), Expected: "trick"}
+	b.SaveServer(serv)
+	HandlerFromMessageCreate(b, b.command("trick"))(nil, messageCreate(gid, cid, uid, "b!trick"))
+}
+
+func TestAntiCheat(t *testing.T) {
+	const (
+		channel      = "300000000000000001"
+		adminChannel = "300000000000000002"
+		macro        = "400000000000000001"
+		script       = "400000000000000002"
+		human        = "400000000000000003"
+	)
+	a := assert.New(t)
+	s := newFakeSession(0)
+	b := newTestBot(t, s)
+	b.conf.AntiCheat = CheatOptions{MinReaction: 500 * time.Millisecond, Window: 5, MinSpread: 0.05}
+	serv := b.NewServer("guild")
+	serv.G.On = true
+	serv.Channels = []string{channel}
+	serv.Admins = []string{"admin"}
+	serv.AdminChannel = adminChannel
+	b.SaveServer(serv)
+
+	grab := func(uid string, reaction time.Duration) {
+		grabAfter(b, "guild", channel, uid, reaction)
+	}
+	admin := func(content string) string {
+		msg := messageCreate("guild", "channel", "admin", content)
+		name := strings.TrimPrefix(strings.Fields(content)[0], "b!")
+		HandlerFromMessageCreate(b, b.command(name))(nil, msg)
+		for _, sent := range s.sent {
+			if sent.ChannelID == "channel" && sent.ID > msg.ID {
+				return sent.Content
+			}
+		}
+		return ""
+	}
+	posted := func() []string {
+		res := []string{}
+		for _, sent := range s.sent {
+			if sent.ChannelID == adminChannel {
+				res = append(res, sent.Content)
+			}
+		}
+		return res
+	}
+
+	t.Run("fast", func(t *testing.T) {
+		grab(macro, 200*time.Millisecond)
+		grab(macro, 100*time.Millisecond)
+		flags := b.Flags("guild", macro)
+		a.Len(flags, 1)
+		a.Equal(RuleFast, flags[0].Rule)
+		a.Len(posted(), 1)
+		a.Contains(posted()[0], U.BuildUserTag(macro))
+	})
+
+	t.Run("regular", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			grab(script, 1200*time.Millisecond)
+		}
+		flags := b.Flags("guild", script)
+		a.Len(flags, 1)
+		a.Equal(RuleRegular, flags[0].Rule)
+
+		for _, reaction := range []time.Duration{time.Second, 3 * time.Second, 2 * time.Second, 5 * time.Second, 1500 * time.Millisecond} {
+			grab(human, reaction)
+		}
+		a.Empty(b.Flags("guild", human))
+		a.Len(b.Flags("guild", ""), 2)
+	})
+
+	t.Run("other servers", func(t *testing.T) {
+		other := b.NewServer("other")
+		other.G.On = true
+		other.Channels = []string{channel}
+		b.SaveServer(other)
+		for i := 0; i < 3; i++ {
+			grabAfter(b, "other", channel, script, 3*time.Second)
+		}
+		reactions := b.lastReactions("guild", script, 5, time.Time{}, b.WithFields(nil))
+		a.Len(reactions, 5)
+		for _, reaction := range reactions {
+			a.InDelta(1200*time.Millisecond, reaction, float64(100*time.Millisecond))
+		}
+		a.Len(b.lastReactions("other", script, 5, time.Time{}, b.WithFields(nil)), 3)
+		a.Empty(b.Flags("other", script))
+	})
+
+	t.Run("review", func(t *testing.T) {
+		a.Contains(admin("b!flags "+U.BuildUserTag(script)), "over their last 5 grabs")
+		a.Contains(admin("b!dismiss <@"+script+">"), "Dismissed 1 flags")
+		a.Len(b.Flags("guild", ""), 1)
+		a.True(b.Flags("guild", script)[0].Reviewed)
+		// The grabs of the dismissed flag do not raise it again
+		count := len(posted())
+		grab(script, 1200*time.Millisecond)
+		a.Len(b.Flags("guild", script), 1)
+		a.Len(posted(), count)
+
+		admin("b!ban <@" + macro + "> show macro")
+		a.Equal("macro", b.GetServer("guild").Banned[macro].Reason)
+		a.Empty(b.Flags("guild", ""))
+		items := b.GetServer("guild").Users[macro]
+		grab(macro, 2*time.Second)
+		a.Equal(items, b.GetServer("guild").Users[macro])
+		a.Contains(b.GetServer("guild").G.Monsters, channel)
+		admin("b!unban <@" + macro + ">")
+		a.NotContains(b.GetServer("guild").Banned, macro)
+
+		item := b.GetServer("guild").Users[human][0]
+		admin("b!revoke <@" + human + "> " + item)
+		a.NotContains(b.GetServer("guild").Users[human], item)
+		admin("b!revoke <@" + script + ">")
+		a.NotContains(b.GetServer("guild").Users, script)
+		for _, sb := range b.GetServer("guild").Lb {
+			a.NotEqual(script, sb.UID)
+		}
+
+		// A full window of regular grabs after the dismissal raises a new flag
+		for i := 0; i < 4; i++ {
+			grab(script, 1200*time.Millisecond)
+		}
+		a.Len(b.Flags("guild", script), 2)
+		a.False(b.Flags("guild", script)[0].Reviewed)
+	})
+}
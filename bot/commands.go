@@ -15,6 +15,9 @@ func (b *Bot) triggered(m *DG.MessageCreate, serv Server, cmd Command) ([]string
 	if cmd.Admin && !serv.IsAdmin(m.Author.ID) {
 		return payload, false
 	}
+	if cmd.Owner && !b.IsOwner(m.Author.ID) {
+		return payload, false
+	}
 
 	tagCommand := b.Mention + " " + cmd.Name
 	fields := strings.Fields(m.Content)
@@ -55,7 +58,7 @@ func (b *Bot) TriggersAnyOtherCommand(p CommandParameters) bool {
 	return false
 }
 
-func SendText(s *DG.Session, i *DG.Interaction, channelID, content string) (*DG.Message, error) {
+func SendText(s Session, i *DG.Interaction, channelID, content string) (*DG.Message, error) {
 	if i == nil {
 		return s.ChannelMessageSend(channelID, content)
 	}
@@ -71,15 +74,52 @@ func SendText(s *DG.Session, i *DG.Interaction, channelID, content string) (*DG.
 	return s.InteractionResponse(i)
 }
 
-func SendEmbed(s *DG.Session, i *DG.Interaction, channelID string, embed *DG.MessageEmbed, components []DG.MessageComponent) (*DG.Message, error) {
+// SendEphemeral replies to an interaction with a message only visible to the interacting user.
+func SendEphemeral(s Session, i *DG.Interaction, content string) error {
+	return s.InteractionRespond(i, &DG.InteractionResponse{
+		Type: DG.InteractionResponseChannelMessageWithSource,
+		Data: &DG.InteractionResponseData{
+			Content: content,
+			Flags:   DG.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// SendFile sends a message with an attached file.
+func SendFile(s Session, i *DG.Interaction, channelID, content string, file *DG.File) (*DG.Message, error) {
 	if i == nil {
-		return s.ChannelMessageSendEmbed(channelID, embed)
+		return s.ChannelMessageSendComplex(channelID, &DG.MessageSend{
+			Content: content,
+			Files:   []*DG.File{file},
+		})
 	}
 	err := s.InteractionRespond(i, &DG.InteractionResponse{
 		Type: DG.InteractionResponseChannelMessageWithSource,
 		Data: &DG.InteractionResponseData{
+			Content: content,
+			Files:   []*DG.File{file},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.InteractionResponse(i)
+}
+
+func SendEmbed(s Session, i *DG.Interaction, channelID string, embed *DG.MessageEmbed, components []DG.MessageComponent, files ...*DG.File) (*DG.Message, error) {
+	if i == nil {
+		return s.ChannelMessageSendComplex(channelID, &DG.MessageSend{
 			Embeds:     []*DG.MessageEmbed{embed},
 			Components: components,
+			Files:      files,
+		})
+	}
+	err := s.InteractionRespond(i, &DG.InteractionResponse{
+		Type: DG.InteractionResponseChannelMessageWithSource,
+		Data: &DG.InteractionResponseData{
+			Embeds:     []*DG.MessageEmbed{embed},
+			Components: components,
+			Files:      files,
 		},
 	})
 	if err != nil {
@@ -91,7 +131,7 @@ func SendEmbed(s *DG.Session, i *DG.Interaction, channelID string, embed *DG.Mes
 func buildOptions(b *Bot) {
 	for i := range b.Commands {
 		if b.Commands[i].appCmd != nil {
-			if b.Commands[i].Admin || b.Commands[i].AlwaysTrigger {
+			if b.Commands[i].Admin || b.Commands[i].Owner || b.Commands[i].AlwaysTrigger {
 				b.Commands[i].appCmd.DefaultMemberPermissions = &DefaultMemberPermissions
 			}
 			b.Commands[i].appCmd.Options = b.Commands[i].DGOptions(b)
@@ -115,16 +155,40 @@ type CommandParameters struct {
 	Options             map[string]interface{}
 	S                   Server
 	IsUserTriggered     bool
+	Log                 Logger // Logger carrying the guild, channel, user and command fields
 }
 
 func (p *CommandParameters) ParseOptionsFromRaws(raws []string, opts Options) error {
-	if len(raws) < len(opts) {
+	if len(raws) < opts.Required() {
 		return fmt.Errorf("not enough arguments for command %s", p.Name)
 	}
-	for i, opt := range opts {
+	i := 0
+	for n, opt := range opts {
+		if opt.Type == TypeAttachment {
+			// Files are attached to the message instead of being part of its content
+			if p.MsgCreate == nil || len(p.MsgCreate.Attachments) == 0 {
+				if opt.Optional {
+					continue
+				}
+				return fmt.Errorf("missing %s attachment", opt.Name)
+			}
+			p.Options[opt.Name] = p.MsgCreate.Attachments[0].URL
+			continue
+		}
+		if i >= len(raws) {
+			break // only optional options remain
+		}
 		raw := raws[i]
+		i++
+		if n == len(opts)-1 && opt.Type == TypeString && len(opt.Choices) == 0 {
+			// The last option takes the rest of the line, eg a reason
+			raw = strings.Join(raws[i-1:], " ")
+		}
 		switch opt.Type {
 		case TypeString:
+			if err := opt.Check(raw); err != nil {
+				return err
+			}
 			p.Options[opt.Name] = raw
 		case TypeInteger:
 			v, err := strconv.Atoi(raw)
@@ -139,9 +203,9 @@ func (p *CommandParameters) ParseOptionsFromRaws(raws []string, opts Options) er
 			}
 			p.Options[opt.Name] = v
 		case TypeUser:
-			v, ok := util.StripChannelTag(raw)
+			v, ok := util.StripUserTag(raw)
 			if !ok {
-				return fmt.Errorf("invalid channel: %s", raw)
+				return fmt.Errorf("invalid user: %s", raw)
 			}
 			p.Options[opt.Name] = v
 		default:
@@ -158,16 +222,22 @@ func (p *CommandParameters) ParseOptionsFromInteraction(i *DG.Interaction, opts
 		optionMap[opt.Name] = opt
 	}
 
-	if len(options) < len(opts) {
+	if len(options) < opts.Required() {
 		return fmt.Errorf("not enough arguments for command %s", p.Name)
 	}
 	for _, opt := range opts {
 		dgOption, ok := optionMap[opt.Name]
+		if !ok && opt.Optional {
+			continue
+		}
 		if !ok {
 			return fmt.Errorf("missing option %s", opt.Name)
 		}
 		switch opt.Type {
 		case TypeString:
+			if err := opt.Check(dgOption.StringValue()); err != nil {
+				return err
+			}
 			p.Options[opt.Name] = dgOption.StringValue()
 		case TypeInteger:
 			p.Options[opt.Name] = int(dgOption.IntValue())
@@ -175,6 +245,12 @@ func (p *CommandParameters) ParseOptionsFromInteraction(i *DG.Interaction, opts
 			p.Options[opt.Name] = dgOption.ChannelValue(nil).ID
 		case TypeUser:
 			p.Options[opt.Name] = dgOption.UserValue(nil).ID
+		case TypeAttachment:
+			resolved := i.ApplicationCommandData().Resolved
+			if resolved == nil || resolved.Attachments[dgOption.Value.(string)] == nil {
+				return fmt.Errorf("missing %s attachment", opt.Name)
+			}
+			p.Options[opt.Name] = resolved.Attachments[dgOption.Value.(string)].URL
 		default:
 			return fmt.Errorf("unknown option type: %s", opt.Type)
 		}
@@ -193,6 +269,7 @@ func ParamsFromInteraction(b *Bot, i *DG.InteractionCreate, name string) Command
 		Options:         map[string]interface{}{},
 		S:               serv,
 		IsUserTriggered: true,
+		Log:             b.CommandLogger(i.GuildID, i.ChannelID, i.Member.User.ID, name),
 	}
 }
 
@@ -207,14 +284,18 @@ func ParamsFromMessageCreate(b *Bot, m *DG.MessageCreate, name string) CommandPa
 		Name:      name,
 		Options:   map[string]interface{}{},
 		S:         serv,
+		Log:       b.CommandLogger(m.GuildID, m.ChannelID, m.Author.ID, name),
 	}
 }
 
 func HandlerFromMessageCreate(b *Bot, cmd Command) func(*DG.Session, *DG.MessageCreate) {
 	return func(s *DG.Session, m *DG.MessageCreate) {
+		if !b.enter() {
+			return
+		}
+		defer b.leave()
 		if cmd.ModifiesServer {
-			b.mutex.Lock()
-			defer b.mutex.Unlock()
+			defer b.lockGuild(m.GuildID)()
 		}
 		p := ParamsFromMessageCreate(b, m, cmd.Name)
 		if p.UID == b.UserID {
@@ -228,11 +309,11 @@ func HandlerFromMessageCreate(b *Bot, cmd Command) func(*DG.Session, *DG.Message
 		p.IsUserTriggered = ok
 		err := p.ParseOptionsFromRaws(raws, cmd.Options)
 		if err != nil {
-			SendText(s, nil, p.CID, err.Error())
+			SendText(b.s, nil, p.CID, err.Error())
 			return
 		}
 		if !cmd.AlwaysTrigger {
-			b.Info("command %s triggered", cmd.Name)
+			p.Log.Info("command triggered")
 		}
 		cmd.Action(b, p)
 	}
@@ -240,25 +321,28 @@ func HandlerFromMessageCreate(b *Bot, cmd Command) func(*DG.Session, *DG.Message
 
 func HandlerFromInteraction(b *Bot, cmd Command) func(*DG.Session, *DG.InteractionCreate) {
 	return func(s *DG.Session, i *DG.InteractionCreate) {
+		if !b.enter() {
+			return
+		}
+		defer b.leave()
 		if cmd.ModifiesServer {
-			b.mutex.Lock()
-			defer b.mutex.Unlock()
+			defer b.lockGuild(i.GuildID)()
 		}
 		p := ParamsFromInteraction(b, i, cmd.Name)
 
 		serv := b.GetServer(p.GID)
-		if cmd.Admin && !serv.IsAdmin(p.UID) {
-			SendText(s, i.Interaction, p.CID, "Command not authorized")
+		if (cmd.Admin && !serv.IsAdmin(p.UID)) || (cmd.Owner && !b.IsOwner(p.UID)) {
+			SendText(b.s, i.Interaction, p.CID, "Command not authorized")
 			return
 		}
 
 		err := p.ParseOptionsFromInteraction(i.Interaction, cmd.Options)
 		if err != nil {
-			SendText(s, nil, p.CID, err.Error())
+			SendText(b.s, nil, p.CID, err.Error())
 			return
 		}
 		if !cmd.AlwaysTrigger {
-			b.Info("command %s triggered", cmd.Name)
+			p.Log.Info("command triggered")
 		}
 		cmd.Action(b, p)
 	}
@@ -267,19 +351,41 @@ func HandlerFromInteraction(b *Bot, cmd Command) func(*DG.Session, *DG.Interacti
 type OptionType string
 
 const (
-	TypeString  OptionType = "string"
-	TypeInteger OptionType = "integer"
-	TypeChannel OptionType = "channel"
-	TypeUser    OptionType = "user"
+	TypeString     OptionType = "string"
+	TypeInteger    OptionType = "integer"
+	TypeChannel    OptionType = "channel"
+	TypeUser       OptionType = "user"
+	TypeAttachment OptionType = "attachment" // URL of a file attached to the command
 )
 
 type Option struct {
 	Name, Description string
 	Type              OptionType
+	Optional          bool     // optional options must come after the required ones
+	Choices           []string // allowed values of a string option, any value if empty
+}
+
+// Check returns an error if the value is not one of the choices of the option.
+func (o Option) Check(value string) error {
+	if len(o.Choices) == 0 || util.Contains(o.Choices, value) {
+		return nil
+	}
+	return fmt.Errorf("invalid %s `%s`, expected one of: %s", o.Name, value, strings.Join(o.Choices, ", "))
 }
 
 type Options []Option
 
+// Required returns the number of options that must be given in the content of a message.
+func (opts Options) Required() int {
+	res := 0
+	for _, opt := range opts {
+		if !opt.Optional && opt.Type != TypeAttachment {
+			res++
+		}
+	}
+	return res
+}
+
 func dgOption(opt Option) (*DG.ApplicationCommandOption, error) {
 	var typ DG.ApplicationCommandOptionType
 	switch opt.Type {
@@ -291,14 +397,21 @@ func dgOption(opt Option) (*DG.ApplicationCommandOption, error) {
 		typ = DG.ApplicationCommandOptionChannel
 	case TypeUser:
 		typ = DG.ApplicationCommandOptionUser
+	case TypeAttachment:
+		typ = DG.ApplicationCommandOptionAttachment
 	default:
 		return nil, fmt.Errorf("unknown option type %s", opt.Type)
 	}
+	choices := []*DG.ApplicationCommandOptionChoice{}
+	for _, choice := range opt.Choices {
+		choices = append(choices, &DG.ApplicationCommandOptionChoice{Name: choice, Value: choice})
+	}
 	return &DG.ApplicationCommandOption{
 		Name:        opt.Name,
 		Description: opt.Description,
 		Type:        typ,
-		Required:    true,
+		Required:    !opt.Optional,
+		Choices:     choices,
 	}, nil
 }
 
@@ -6,7 +6,10 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"time"
 
+	L "github.com/ashyaa/birtho/log"
+	R "github.com/ashyaa/birtho/render"
 	U "github.com/ashyaa/birtho/util"
 	"github.com/koffeinsource/go-imgur"
 	"github.com/koffeinsource/go-klogger"
@@ -33,6 +36,15 @@ type Item struct {
 	Range  Range   `json:"range,omitempty" yaml:"range,omitempty"`
 }
 
+func (i Item) Rarity() R.Rarity {
+	if i.Chance < 20 {
+		return R.Rare
+	} else if i.Chance < 50 {
+		return R.Uncommon
+	}
+	return R.Common
+}
+
 func (i Item) Description(hidden bool) string {
 	rarity := "🔸"
 	if i.Chance < 20 {
@@ -101,13 +113,26 @@ func (m Monster) RandomItem(rng U.RNG, log *LR.Logger) Item {
 }
 
 type Config struct {
-	AppID             int       `json:"app-id" yaml:"app-id"`
-	ClientID          int       `json:"client-id" yaml:"client-id"`
-	PublicKey         string    `json:"public-key" yaml:"public-key"`
-	ImgurClientID     string    `json:"imgur-client-id" yaml:"imgur-client-id"`
-	ImgurClientSecret string    `json:"imgur-client-secret" yaml:"imgur-client-secret"`
-	Token             string    `json:"token" yaml:"token"`
-	Monsters          []Monster `json:"monsters" yaml:"monsters"`
+	AppID             int           `json:"app-id" yaml:"app-id"`
+	ClientID          int           `json:"client-id" yaml:"client-id"`
+	PublicKey         string        `json:"public-key" yaml:"public-key"`
+	ImgurClientID     string        `json:"imgur-client-id" yaml:"imgur-client-id"`
+	ImgurClientSecret string        `json:"imgur-client-secret" yaml:"imgur-client-secret"`
+	Token             string        `json:"token" yaml:"token"`
+	Owners            []string      `json:"owners,omitempty" yaml:"owners,omitempty"` // users running the bot, allowed to use the commands acting on the whole bot
+	Log               L.Options     `json:"log,omitempty" yaml:"log,omitempty"`
+	HealthAddr        string        `json:"health-addr,omitempty" yaml:"health-addr,omitempty"`
+	ShutdownDeadline  time.Duration `json:"shutdown-deadline,omitempty" yaml:"shutdown-deadline,omitempty"`
+	Backup            BackupOptions `json:"backup,omitempty" yaml:"backup,omitempty"`
+	GrabRetention     time.Duration `json:"grab-retention,omitempty" yaml:"grab-retention,omitempty"` // older grabs are deleted, kept forever if 0
+	Pity              PityOptions   `json:"pity,omitempty" yaml:"pity,omitempty"`
+	BadLuck           LuckOptions   `json:"bad-luck-protection,omitempty" yaml:"bad-luck-protection,omitempty"`
+	AntiSpam          SpamOptions   `json:"anti-spam,omitempty" yaml:"anti-spam,omitempty"`
+	AntiCheat         CheatOptions  `json:"anti-cheat,omitempty" yaml:"anti-cheat,omitempty"`
+	TextMenus         bool          `json:"text-menus,omitempty" yaml:"text-menus,omitempty"` // do not render leaderboards and scoreboards as images
+	MenuTTL           time.Duration `json:"menu-ttl,omitempty" yaml:"menu-ttl,omitempty"`     // pages of the menus stop changing after it, never if 0
+	Seed              int64         `json:"seed,omitempty" yaml:"seed,omitempty"`             // seed of the game rolls, from the clock if 0
+	Monsters          []Monster     `json:"monsters" yaml:"monsters"`
 	filepath          string
 }
 
@@ -1,20 +1,157 @@
 package bot
 
-import "github.com/asdine/storm/v3"
+import (
+	"errors"
+	"time"
+
+	"github.com/asdine/storm/v3"
+)
+
+// DefaultDBPath is the database of the running bot.
+const DefaultDBPath = "app.db"
 
 func (b *Bot) OpenDB() {
 	var err error
-	b.db, err = storm.Open("app.db")
+	b.db, err = storm.Open(DefaultDBPath)
 	if err != nil {
 		b.FatalE(err, "opening database")
 	}
+	if err = b.migrate(); err != nil {
+		b.FatalE(err, "migrating database")
+	}
+}
+
+// migrations upgrade the databases of older versions, in order. The version of a database is the
+// number of migrations applied to it.
+var migrations = []func(b *Bot) error{
+	(*Bot).migratePacks,
+	(*Bot).migratePlayerIndexes,
+	(*Bot).migrateGrabStamps,
+	(*Bot).migrateMinDelay,
+	(*Bot).migrateGuildStamps,
+}
+
+// migrate applies the migrations the database misses.
+func (b *Bot) migrate() error {
+	version := 0
+	err := b.db.Get(metaBucket, "version", &version)
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		return err
+	}
+	for ; version < len(migrations); version++ {
+		b.Info("migrating database to version %d", version+1)
+		if err := migrations[version](b); err != nil {
+			return err
+		}
+		if err := b.db.Set(metaBucket, "version", version+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metaBucket stores the version of the database.
+const metaBucket = "meta"
+
+// reIndex rebuilds the indexes of a type, if any record of it is stored.
+func (b *Bot) reIndex(data interface{}) error {
+	err := b.db.ReIndex(data)
+	if errors.Is(err, storm.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// migratePacks considers the servers saved before the item packs were tracked gathered with the
+// current pack, and indexes Global.
+func (b *Bot) migratePacks() error {
+	servers, err := b.Servers()
+	if err != nil {
+		return err
+	}
+	for _, serv := range servers {
+		if serv.Pack != "" {
+			continue
+		}
+		b.Info("server %s: collections marked as gathered with the current item pack", serv.ID)
+		serv.Pack = b.pack
+		if err := b.db.Save(&serv); err != nil {
+			return err
+		}
+	}
+	return b.reIndex(&Server{})
+}
+
+// migratePlayerIndexes indexes the players of the grabs and the flags.
+func (b *Bot) migratePlayerIndexes() error {
+	if err := b.reIndex(&GrabEvent{}); err != nil {
+		return err
+	}
+	return b.reIndex(&CheatFlag{})
+}
+
+// migrateGrabStamps stamps the grabs recorded before the time windows were indexed.
+func (b *Bot) migrateGrabStamps() error {
+	var events []GrabEvent
+	err := b.db.All(&events)
+	if errors.Is(err, storm.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if event.GuildStamp != "" {
+			continue
+		}
+		if err := b.saveGrab(&event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateGuildStamps stamps the grabs recorded before the time windows were indexed per server.
+func (b *Bot) migrateGuildStamps() error {
+	return b.migrateGrabStamps()
+}
+
+// legacyMinDelay is the default minimum cooldown stored by older versions, 120 nanoseconds instead
+// of 120 seconds.
+const legacyMinDelay = 120
+
+// migrateMinDelay gives the servers created with the legacy minimum cooldown the default one.
+func (b *Bot) migrateMinDelay() error {
+	servers, err := b.Servers()
+	if err != nil {
+		return err
+	}
+	for _, serv := range servers {
+		if serv.G.MinDelay != legacyMinDelay {
+			continue
+		}
+		serv.G.MinDelay = DefaultMinDelay
+		if err := b.db.Save(&serv); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (b *Bot) GetServer(id string) Server {
+	res, err := b.FindServer(id)
+	if err != nil {
+		return b.NewServer(id)
+	}
+	return res
+}
+
+// FindServer returns the stored server, or an error if the guild never used the bot.
+func (b *Bot) FindServer(id string) (Server, error) {
 	var res Server
 	err := b.db.One("ID", id, &res)
 	if err != nil {
-		return b.NewServer(id)
+		return res, err
 	}
 	// Safety checks
 	if res.G.Monsters == nil {
@@ -23,10 +160,17 @@ func (b *Bot) GetServer(id string) Server {
 	if res.Users == nil {
 		res.Users = make(map[string][]string)
 	}
-	if IsHistoryInvalid(res.G.LastMessages) {
-		res.G.LastMessages = NewHistory()
+	if res.G.ChannelStates == nil {
+		res.G.ChannelStates = make(map[string]*ChannelState)
 	}
-	return res
+	for cid, state := range res.G.ChannelStates {
+		if state == nil {
+			res.G.ChannelStates[cid] = newChannelState(time.Now())
+		} else if IsHistoryInvalid(state.LastMessages) {
+			state.LastMessages = NewHistory()
+		}
+	}
+	return res, nil
 }
 
 func (b *Bot) NewServer(id string) Server {
@@ -38,12 +182,14 @@ func (b *Bot) NewServer(id string) Server {
 			MinDelay:      DefaultMinDelay,
 			VariableDelay: DefaultVariableDelay,
 			StayTime:      DefaultStayTime,
-			LastMessages:  NewHistory(),
+			ChannelStates: make(map[string]*ChannelState),
+			SeasonStart:   time.Now(),
 		},
 		Channels: make([]string, 0),
 		Admins:   make([]string, 0),
 		Users:    make(map[string][]string),
 		Lb:       make(Leaderboard, 0),
+		Pack:     b.pack,
 	}
 	b.db.Save(&serv)
 	return serv
@@ -0,0 +1,244 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	DG "github.com/bwmarrin/discordgo"
+)
+
+// Export formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+var exportFormats = []string{FormatJSON, FormatCSV}
+
+var exportContentTypes = map[string]string{
+	FormatJSON: "application/json",
+	FormatCSV:  "text/csv",
+}
+
+// Duration is a duration exported as a Go duration string, eg "2m0s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	res, err := time.ParseDuration(s)
+	*d = Duration(res)
+	return err
+}
+
+type ExportSettings struct {
+	Prefix   string   `json:"prefix"`
+	On       bool     `json:"on"`
+	Finished bool     `json:"finished"`
+	Winner   string   `json:"winner,omitempty"`
+	MinDelay Duration `json:"min-delay"`
+	MaxDelay Duration `json:"max-delay"`
+	StayTime Duration `json:"stay-time"`
+	Channels []string `json:"channels"`
+	Admins   []string `json:"admins"`
+	Global   bool     `json:"global"`
+}
+
+type ExportItem struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Monster string `json:"monster"`
+	Rarity  string `json:"rarity"`
+	Points  int    `json:"points"`
+}
+
+type ExportPlayer struct {
+	Rank  string       `json:"rank"`
+	UID   string       `json:"uid"`
+	Name  string       `json:"name"`
+	Score int          `json:"score"`
+	Items []ExportItem `json:"items"`
+}
+
+// Export is the game data of a server.
+type Export struct {
+	Guild    string         `json:"guild"`
+	Exported time.Time      `json:"exported"`
+	Pack     string         `json:"pack"`
+	Settings ExportSettings `json:"settings"`
+	Players  []ExportPlayer `json:"players"`
+}
+
+// Export returns the game data of the server. Scores are computed from the stored collections and
+// names come from the stored leaderboard, so it does not need a Discord session.
+func (b *Bot) Export(serv Server) Export {
+	monsters := make(map[string]string)
+	for _, m := range b.Monsters {
+		for _, item := range m.Items {
+			monsters[item.ID] = m.Name
+		}
+	}
+	names := make(map[string]string)
+	for _, sb := range serv.Lb {
+		names[sb.UID] = sb.Name
+	}
+
+	lb := Leaderboard{}
+	items := make(map[string][]ExportItem)
+	for uid, ids := range serv.Users {
+		sb := ScoreBoard{UID: uid, Name: names[uid]}
+		for _, id := range ids {
+			item, ok := b.Items[id]
+			if !ok {
+				continue
+			}
+			sb.Score += item.Points
+			items[uid] = append(items[uid], ExportItem{
+				ID:      id,
+				Name:    item.Name,
+				Monster: monsters[id],
+				Rarity:  item.Rarity().String(),
+				Points:  item.Points,
+			})
+		}
+		lb = append(lb, sb)
+	}
+	sort.Slice(lb, func(i, j int) bool {
+		return lb[i].UID < lb[j].UID
+	})
+	lb.sort()
+
+	res := Export{
+		Guild:    serv.ID,
+		Exported: time.Now(),
+		Pack:     b.pack,
+		Settings: ExportSettings{
+			Prefix:   serv.Prefix,
+			On:       serv.G.On,
+			Finished: serv.G.Finished,
+			Winner:   serv.G.Winner,
+			MinDelay: Duration(serv.G.MinDelay),
+			MaxDelay: Duration(serv.G.MinDelay + time.Duration(serv.G.VariableDelay-1)*time.Second),
+			StayTime: Duration(serv.G.StayTime),
+			Channels: serv.Channels,
+			Admins:   serv.Admins,
+			Global:   serv.Global,
+		},
+		Players: []ExportPlayer{},
+	}
+	for _, sb := range lb {
+		playerItems := items[sb.UID]
+		sort.Slice(playerItems, func(i, j int) bool {
+			return playerItems[i].ID < playerItems[j].ID
+		})
+		res.Players = append(res.Players, ExportPlayer{
+			Rank:  sb.Rank,
+			UID:   sb.UID,
+			Name:  sb.Name,
+			Score: sb.Score,
+			Items: playerItems,
+		})
+	}
+	return res
+}
+
+// Encode returns the export in the given format. In CSV, each line is an item of a player, and the
+// settings are written as leading comment lines starting with '#'.
+func (e Export) Encode(format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(e, "", "  ")
+	case FormatCSV:
+		return e.csv()
+	}
+	return nil, fmt.Errorf("unknown export format %s", format)
+}
+
+// csvEscaped are the first characters of the text cells escaped in CSV exports: spreadsheets read
+// the cells starting with one of the first four as formulas, and the quote keeps the escape
+// reversible.
+const csvEscaped = "=+-@'"
+
+// csvText escapes a text cell with a leading quote, so that the player names are not run as
+// formulas when the export is opened in a spreadsheet.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune(csvEscaped, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvUnescape returns the text of a cell escaped by csvText.
+func csvUnescape(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvEscaped, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+func (e Export) csv() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	settings, err := json.Marshal(e.Settings)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(buf, "# guild: %s\n# exported: %s\n# pack: %s\n# settings: %s\n",
+		e.Guild, e.Exported.Format(time.RFC3339), e.Pack, settings)
+
+	w := csv.NewWriter(buf)
+	w.Write([]string{"rank", "uid", "name", "score", "item", "item_name", "monster", "rarity", "points"})
+	for _, p := range e.Players {
+		player := []string{p.Rank, p.UID, csvText(p.Name), strconv.Itoa(p.Score)}
+		if len(p.Items) == 0 {
+			w.Write(append(player, "", "", "", "", ""))
+		}
+		for _, item := range p.Items {
+			w.Write(append(player, item.ID, csvText(item.Name), csvText(item.Monster), item.Rarity, strconv.Itoa(item.Points)))
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// ExportGuild returns the encoded game data of a stored server.
+func (b *Bot) ExportGuild(gid, format string) ([]byte, error) {
+	serv, err := b.FindServer(gid)
+	if err != nil {
+		return nil, fmt.Errorf("server %s: %w", gid, err)
+	}
+	return b.Export(serv).Encode(format)
+}
+
+func ExportData(b *Bot, p CommandParameters) {
+	format, ok := p.Options["format"].(string)
+	if !ok {
+		format = FormatJSON
+	}
+	data, err := b.Export(p.S).Encode(format)
+	if err != nil {
+		p.Log.ErrorE(err, "exporting server")
+		SendText(b.s, p.I, p.CID, "Export failed.")
+		return
+	}
+	name := fmt.Sprintf("birtho-%s-%s.%s", p.GID, time.Now().Format("20060102-150405"), format)
+	_, err = SendFile(b.s, p.I, p.CID, "Game data of the server:", &DG.File{
+		Name:        name,
+		ContentType: exportContentTypes[format],
+		Reader:      bytes.NewReader(data),
+	})
+	if err != nil {
+		p.Log.ErrorE(err, "sending export")
+	}
+}
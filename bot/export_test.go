@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	LR "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	serv := b.NewServer("guild")
+	serv.Users["a"] = []string{"m1i1", "m1i3"}
+	serv.Users["b"] = []string{"m1i2", "unknown"}
+	serv.Users["c"] = []string{}
+	serv.Lb = Leaderboard{{UID: "a", Name: "alice", Score: 11}}
+	serv.Channels = []string{"channel"}
+
+	export := b.Export(serv)
+	a.Equal("guild", export.Guild)
+	a.Equal([]string{"channel"}, export.Settings.Channels)
+	a.Len(export.Players, 3)
+	a.Equal(ExportPlayer{Rank: "1st", UID: "a", Name: "alice", Score: 11, Items: []ExportItem{
+		{ID: "m1i1", Name: "Candy", Monster: "Ghost", Rarity: "common", Points: 1},
+		{ID: "m1i3", Name: "Skull", Monster: "Ghost", Rarity: "rare", Points: 10},
+	}}, export.Players[0])
+	a.Equal(5, export.Players[1].Score)
+	a.Equal("3rd", export.Players[2].Rank)
+
+	t.Run("json", func(t *testing.T) {
+		a := assert.New(t)
+		data, err := export.Encode(FormatJSON)
+		a.NoError(err)
+		var decoded Export
+		a.NoError(json.Unmarshal(data, &decoded))
+		a.Equal(export.Players, decoded.Players)
+		a.Equal(export.Settings, decoded.Settings)
+		a.Contains(string(data), `"min-delay": "2m0s"`)
+	})
+	t.Run("durations", func(t *testing.T) {
+		a := assert.New(t)
+		var settings ExportSettings
+		a.NoError(json.Unmarshal([]byte(`{"min-delay":"2m0s","stay-time":"1m30s"}`), &settings))
+		a.Equal(Duration(2*time.Minute), settings.MinDelay)
+		a.Equal(Duration(90*time.Second), settings.StayTime)
+		a.Error(json.Unmarshal([]byte(`{"max-delay":120000000000}`), &settings))
+		a.Error(json.Unmarshal([]byte(`{"max-delay":"soon"}`), &settings))
+	})
+	t.Run("csv", func(t *testing.T) {
+		a := assert.New(t)
+		data, err := export.Encode(FormatCSV)
+		a.NoError(err)
+		r := csv.NewReader(strings.NewReader(string(data)))
+		r.Comment = '#'
+		records, err := r.ReadAll()
+		a.NoError(err)
+		a.Len(records, 1+2+1+1) // header, 2 items of a, 1 of b, c without items
+		a.Equal([]string{"1st", "a", "alice", "11", "m1i3", "Skull", "Ghost", "rare", "10"}, records[2])
+		a.Equal([]string{"3rd", "c", "", "0", "", "", "", "", ""}, records[4])
+	})
+	t.Run("formulas", func(t *testing.T) {
+		a := assert.New(t)
+		names := []string{"=1+1", "+1", "-1", "@SUM(A1)", "'=1", "'quoted", "al=ice"}
+		formulas := Export{Players: []ExportPlayer{}}
+		for i, name := range names {
+			formulas.Players = append(formulas.Players, ExportPlayer{UID: strconv.Itoa(i), Name: name})
+		}
+		data, err := formulas.Encode(FormatCSV)
+		a.NoError(err)
+		r := csv.NewReader(strings.NewReader(string(data)))
+		r.Comment = '#'
+		records, err := r.ReadAll()
+		a.NoError(err)
+		a.Equal([]string{"'=1+1", "'+1", "'-1", "'@SUM(A1)", "''=1", "''quoted", "al=ice"}, []string{
+			records[1][2], records[2][2], records[3][2], records[4][2], records[5][2], records[6][2], records[7][2],
+		})
+
+		parsed, err := ParseExport(data)
+		a.NoError(err)
+		for i, name := range names {
+			a.Equal(name, parsed.Players[i].Name)
+		}
+	})
+}
+
+func TestOfflineExport(t *testing.T) {
+	a := assert.New(t)
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := storm.Open(path)
+	a.NoError(err)
+	a.NoError(db.Save(&Server{ID: "guild", Users: map[string][]string{"a": {"m1i2"}}}))
+
+	log := LR.New()
+	log.SetOutput(io.Discard)
+	_, err = Open(log, testConfig(), path, true)
+	a.ErrorContains(err, "in use") // the database is locked by the running bot
+	a.NoError(db.Close())
+
+	b, err := Open(log, testConfig(), path, true)
+	a.NoError(err)
+	defer b.Close()
+	data, err := b.ExportGuild("guild", FormatJSON)
+	a.NoError(err)
+	a.Contains(string(data), `"name": "Lantern"`)
+	_, err = b.ExportGuild("other", FormatJSON)
+	a.Error(err)
+}
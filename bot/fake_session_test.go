@@ -0,0 +1,267 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	U "github.com/ashyaa/birtho/util"
+	DG "github.com/bwmarrin/discordgo"
+	LR "github.com/sirupsen/logrus"
+)
+
+// fakeSession is an in-memory Session where every REST call takes latency to complete.
+type fakeSession struct {
+	latency  time.Duration
+	calls    atomic.Int64
+	mutex    sync.Mutex
+	sent     map[string]*DG.Message // sent and edited messages, by message ID
+	failing  sync.Map               // IDs of the messages whose edition fails
+	files    sync.Map               // content of the files sent, by message ID
+	replies  []*DG.InteractionResponse
+	members  []*DG.Member // guild members, by increasing ID
+	channels []string     // IDs of the guild channels
+}
+
+func newFakeSession(latency time.Duration) *fakeSession {
+	return &fakeSession{latency: latency, sent: make(map[string]*DG.Message)}
+}
+
+var snowflakeCounter atomic.Int64
+
+// snowflake returns a new unique Discord ID created now.
+func snowflake() string {
+	return snowflakeAt(time.Now())
+}
+
+// snowflakeAt returns a new unique Discord ID created at the given time.
+func snowflakeAt(t time.Time) string {
+	ms := t.UnixMilli() - 1420070400000
+	return strconv.FormatInt(ms<<22|(snowflakeCounter.Add(1)&0x3FFFFF), 10)
+}
+
+func (f *fakeSession) call() {
+	f.calls.Add(1)
+	time.Sleep(f.latency)
+}
+
+func (f *fakeSession) store(msg *DG.Message) *DG.Message {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.sent[msg.ID] = msg
+	return msg
+}
+
+func (f *fakeSession) message(mID string) *DG.Message {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.sent[mID]
+}
+
+func (f *fakeSession) ChannelMessage(channelID, messageID string, _ ...DG.RequestOption) (*DG.Message, error) {
+	f.call()
+	if msg := f.message(messageID); msg != nil {
+		return msg, nil
+	}
+	return &DG.Message{ID: messageID, ChannelID: channelID}, nil
+}
+
+func (f *fakeSession) ChannelMessageSend(channelID, content string, _ ...DG.RequestOption) (*DG.Message, error) {
+	f.call()
+	return f.store(&DG.Message{ID: snowflake(), ChannelID: channelID, Content: content}), nil
+}
+
+func (f *fakeSession) ChannelMessageSendComplex(channelID string, data *DG.MessageSend, _ ...DG.RequestOption) (*DG.Message, error) {
+	f.call()
+	msg := &DG.Message{ID: snowflake(), ChannelID: channelID, Content: data.Content, Embeds: data.Embeds, Components: data.Components}
+	for _, file := range data.Files {
+		content, _ := io.ReadAll(file.Reader)
+		f.files.Store(msg.ID, content)
+	}
+	return f.store(msg), nil
+}
+
+func (f *fakeSession) ChannelMessageSendEmbed(channelID string, embed *DG.MessageEmbed, _ ...DG.RequestOption) (*DG.Message, error) {
+	f.call()
+	return f.store(&DG.Message{ID: snowflake(), ChannelID: channelID, Embeds: []*DG.MessageEmbed{embed}}), nil
+}
+
+func (f *fakeSession) ChannelMessageEditComplex(m *DG.MessageEdit, _ ...DG.RequestOption) (*DG.Message, error) {
+	f.call()
+	if _, ok := f.failing.Load(m.ID); ok {
+		return nil, errors.New("unknown message")
+	}
+	msg := &DG.Message{ID: m.ID, ChannelID: m.Channel}
+	if m.Embeds != nil {
+		msg.Embeds = *m.Embeds
+	}
+	if m.Components != nil {
+		msg.Components = *m.Components
+	}
+	return f.store(msg), nil
+}
+
+func (f *fakeSession) ChannelMessageEditEmbed(channelID, messageID string, embed *DG.MessageEmbed, _ ...DG.RequestOption) (*DG.Message, error) {
+	f.call()
+	return f.store(&DG.Message{ID: messageID, ChannelID: channelID, Embeds: []*DG.MessageEmbed{embed}}), nil
+}
+
+func (f *fakeSession) MessageReactionAdd(_, _, _ string, _ ...DG.RequestOption) error {
+	f.call()
+	return nil
+}
+
+func (f *fakeSession) InteractionRespond(_ *DG.Interaction, resp *DG.InteractionResponse, _ ...DG.RequestOption) error {
+	f.call()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.replies = append(f.replies, resp)
+	return nil
+}
+
+// lastReply returns the last interaction response.
+func (f *fakeSession) lastReply() *DG.InteractionResponse {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(f.replies) == 0 {
+		return nil
+	}
+	return f.replies[len(f.replies)-1]
+}
+
+func (f *fakeSession) InteractionResponse(i *DG.Interaction, _ ...DG.RequestOption) (*DG.Message, error) {
+	f.call()
+	return f.store(&DG.Message{ID: snowflake(), ChannelID: i.ChannelID}), nil
+}
+
+func (f *fakeSession) GuildMember(_, userID string, _ ...DG.RequestOption) (*DG.Member, error) {
+	f.call()
+	return &DG.Member{User: &DG.User{ID: userID, Username: "user" + userID}}, nil
+}
+
+func (f *fakeSession) GuildMembers(_, after string, limit int, _ ...DG.RequestOption) ([]*DG.Member, error) {
+	f.call()
+	start := 0
+	if after != "" {
+		id, _ := strconv.ParseUint(after, 10, 64)
+		start = sort.Search(len(f.members), func(i int) bool {
+			other, _ := strconv.ParseUint(f.members[i].User.ID, 10, 64)
+			return other > id
+		})
+	}
+	end := start + limit
+	if end > len(f.members) {
+		end = len(f.members)
+	}
+	return f.members[start:end], nil
+}
+
+// addMembers adds n guild members named after their index.
+func (f *fakeSession) addMembers(n int) []string {
+	res := []string{}
+	for i := 0; i < n; i++ {
+		uid := snowflake()
+		f.members = append(f.members, &DG.Member{User: &DG.User{ID: uid, Username: fmt.Sprintf("member%d", i)}})
+		res = append(res, uid)
+	}
+	return res
+}
+
+func (f *fakeSession) GuildChannels(_ string, _ ...DG.RequestOption) ([]*DG.Channel, error) {
+	f.call()
+	res := []*DG.Channel{}
+	for _, cid := range f.channels {
+		res = append(res, &DG.Channel{ID: cid})
+	}
+	return res, nil
+}
+
+func (f *fakeSession) UserChannelCreate(recipientID string, _ ...DG.RequestOption) (*DG.Channel, error) {
+	f.call()
+	return &DG.Channel{ID: "dm" + recipientID, Type: DG.ChannelTypeDM}, nil
+}
+
+// newTestBot returns a bot backed by a temporary database and the given session, with a single
+// monster giving three items.
+func newTestBot(t testing.TB, s Session) *Bot {
+	db, err := storm.Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Bolt.NoSync = true
+	log := LR.New()
+	log.SetOutput(io.Discard)
+	b := &Bot{
+		s:                   s,
+		db:                  db,
+		Log:                 log,
+		UserID:              "100000000000000000",
+		Mention:             U.BuildUserTag("100000000000000000"),
+		Items:               make(map[string]Item),
+		InteractionHandlers: make(InteractionHandlers),
+		guildLocks:          make(map[string]*sync.Mutex),
+		ranks:               make(map[string]*rankIndex),
+		members:             newMemberCache(),
+		rng:                 U.NewSeededRNG(1),
+		thumbnails: newThumbnailCache(func(string) (image.Image, error) {
+			return nil, errors.New("offline")
+		}),
+		confLoaded: time.Now(),
+	}
+	b.buildGameData(testConfig())
+	b.Commands = append(b.Commands, commandList...)
+	b.Menus = NewMenuManager(s, b.WithFields(LR.Fields{}), MenuExpiryInterval)
+	t.Cleanup(func() {
+		// Prevent pending spawn timers from using the closed database
+		b.closingMutex.Lock()
+		b.closing = true
+		b.closingMutex.Unlock()
+		b.Menus.Close(context.Background())
+		db.Close()
+	})
+	return b
+}
+
+// testConfig returns a configuration with a single monster giving three items.
+func testConfig() Config {
+	return Config{Monsters: []Monster{{
+		ID:   1,
+		Name: "Ghost",
+		URL:  "https://example.com/ghost.png",
+		Items: []Item{
+			{Name: "Candy", Chance: 50, Points: 1},
+			{Name: "Lantern", Chance: 35, Points: 5},
+			{Name: "Skull", Chance: 15, Points: 10},
+		},
+	}}}
+}
+
+func (b *Bot) command(name string) Command {
+	for _, cmd := range b.Commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	panic("unknown command " + name)
+}
+
+// messageCreate returns a new message event in the given guild and channel.
+func messageCreate(gid, cid, uid, content string) *DG.MessageCreate {
+	return &DG.MessageCreate{Message: &DG.Message{
+		ID:        snowflake(),
+		GuildID:   gid,
+		ChannelID: cid,
+		Content:   content,
+		Author:    &DG.User{ID: uid},
+	}}
+}
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -12,12 +13,16 @@ import (
 )
 
 func (b *Bot) RandomMonster() Monster {
+	return b.randomMonster(b.rng)
+}
+
+func (b *Bot) randomMonster(rng U.RNG) Monster {
 	if b.EqualMonsterChances {
-		index := b.rng.Intn(len(b.MonsterIds))
+		index := rng.Intn(len(b.MonsterIds))
 		key := b.MonsterIds[index]
 		return b.Monsters[key]
 	}
-	number := b.rng.Intn(10000) + 1
+	number := rng.Intn(10000) + 1
 	for _, m := range b.Monsters {
 		if m.Range.Belongs(number) {
 			return m
@@ -27,6 +32,35 @@ func (b *Bot) RandomMonster() Monster {
 	return Monster{}
 }
 
+// RandomMonsterIn returns a random monster of a channel pool, or of all monsters if the pool is
+// empty.
+func (b *Bot) RandomMonsterIn(pool map[string]float64) Monster {
+	return b.randomMonsterIn(b.rng, pool)
+}
+
+func (b *Bot) randomMonsterIn(rng U.RNG, pool map[string]float64) Monster {
+	ids := []string{}
+	total := 0.0
+	for id, weight := range pool {
+		if _, ok := b.Monsters[id]; ok && weight > 0 {
+			ids = append(ids, id)
+			total += weight
+		}
+	}
+	if len(ids) == 0 {
+		return b.randomMonster(rng)
+	}
+	sort.Strings(ids) // the same rolls give the same monsters
+	roll := rng.Float64() * total
+	for _, id := range ids {
+		if roll < pool[id] {
+			return b.Monsters[id]
+		}
+		roll -= pool[id]
+	}
+	return b.Monsters[ids[len(ids)-1]] // rounding errors
+}
+
 func Play(b *Bot, p CommandParameters) {
 	arg := strings.ToLower(p.Options["state"].(string))
 	if arg != "on" && arg != "off" {
@@ -50,8 +84,13 @@ func Play(b *Bot, p CommandParameters) {
 
 func Reset(b *Bot, p CommandParameters) {
 	p.S.Users = make(map[string][]string)
+	p.S.Lb = make(Leaderboard, 0)
+	p.S.Pity = nil
+	b.dropRankIndex(p.GID)
+	p.S.Pack = b.pack
 	p.S.G.Finished = false
 	p.S.G.Winner = ""
+	p.S.G.SeasonStart = time.Now()
 	b.SaveServer(p.S)
 
 	msg := "Cleared all players' item list and reset the game status!"
@@ -64,14 +103,25 @@ func Spawn(b *Bot, p CommandParameters) {
 		return
 	}
 	if isManualCommand {
-		b.Info("command %s triggered manually", p.Name)
+		p.Log.Info("command triggered manually")
+		p.S.G.Channel(p.CID).ResetSpawnRate()
 	} else {
 		if b.TriggersAnyOtherCommand(p) {
 			return
 		}
-		if !p.S.G.Spawns(b.rng) {
-			p.S.G.UpdateSpawnRate(b.rng, p.MsgCreate.Message)
-			b.Info("new spawn rate: %d%%", p.S.G.SpawnRate)
+		msg, err := messageOf(p.MsgCreate.Message)
+		if err != nil {
+			p.Log.WarnE(err, "message time")
+			return
+		}
+		state := p.S.G.Channel(p.CID)
+		weight, reason := b.conf.AntiSpam.weigh(p.MsgCreate.Message, &msg, state.LastMessages)
+		if weight < 1 && b.rng.Float64() >= weight {
+			p.Log.Debug("message ignored: %s", reason)
+			return
+		}
+		if !p.S.G.Policy().Roll(b.rng, state, msg) {
+			p.Log.With("rate", state.SpawnRate).Debug("no spawn with the %s policy", p.S.G.PolicyName())
 			b.SaveServer(p.S)
 			return
 		}
@@ -80,13 +130,10 @@ func Spawn(b *Bot, p CommandParameters) {
 		if err == nil {
 			userName = U.MemberName(member)
 		}
-		b.Info("command %s triggered by %s", p.Name, userName)
-		p.S.G.LastMessages = p.S.G.LastMessages.Update(p.MsgCreate.Message)
+		p.Log.Info("command triggered by %s", userName)
 	}
 
-	p.S.G.ResetSpawnRate()
-
-	monster := b.RandomMonster()
+	monster := b.RandomMonsterIn(p.S.G.Channel(p.CID).Pool)
 	spawn := MonsterSpawn{
 		ID:       strconv.Itoa(monster.ID),
 		Expected: "trick",
@@ -107,19 +154,19 @@ func Spawn(b *Bot, p CommandParameters) {
 		nil,
 	)
 	if err != nil {
-		b.ErrorE(err, "spawn message")
+		p.Log.ErrorE(err, "spawn message")
 		return
 	}
 	spawn.Message = msg.ID
 	p.S.G.Monsters[p.CID] = spawn
-	p.S.Cooldown(b.rng)
+	p.S.ChannelCooldown(b.rng, p.CID)
 	b.SaveServer(p.S)
 
-	time.AfterFunc(p.S.G.StayTime, func() {
+	b.afterFunc(p.S.G.StayTime, func() {
+		defer b.lockGuild(p.GID)()
 		curServ := b.GetServer(p.GID)
 		spawn, ok := curServ.G.Monsters[p.CID]
-		b.Info("spawn message: %s", spawn.Message)
-		b.Info("actual message: %s", msg.ID)
+		p.Log.Debug("spawn message: %s, actual message: %s", spawn.Message, msg.ID)
 		if !ok || spawn.Message != msg.ID {
 			return
 		}
@@ -146,6 +193,9 @@ func Grab(b *Bot, p CommandParameters) {
 	if !ok {
 		return
 	}
+	if p.S.Excluded(p.UID) {
+		return
+	}
 
 	if _, ok = p.S.Users[p.UID]; !ok {
 		p.S.Users[p.UID] = make([]string, 0)
@@ -153,7 +203,7 @@ func Grab(b *Bot, p CommandParameters) {
 
 	if p.Name == spawn.Expected {
 		monster := b.Monsters[spawn.ID]
-		item := monster.RandomItem(b.rng, b.Log)
+		item := b.rollItem(b.rng, monster, &p.S, p.UID)
 		text := fmt.Sprintf("As a thank you for your kindness, **%s** gives %s one **%s**",
 			monster.Name, U.BuildUserTag(p.UID), item.Description(false))
 		duplicate := U.Contains(p.S.Users[p.UID], item.ID)
@@ -166,8 +216,9 @@ func Grab(b *Bot, p CommandParameters) {
 			SetImage(monster.URL).MessageEmbed)
 		b.s.MessageReactionAdd(p.CID, p.MsgCreate.ID, "✅")
 		p.S.Users[p.UID] = U.AppendUnique(p.S.Users[p.UID], item.ID)
+		b.checkGrab(p.S, b.recordGrab(p.GID, p.UID, item, duplicate, spawn.Message, p.MsgCreate.ID, p.Log), p.Log)
 		if !duplicate {
-			p.S = b.updateScore(p.UID, p.S)
+			p.S, _ = b.updateScore(p.UID, p.S)
 		}
 		if !p.S.G.Finished && b.GetUserScore(p.UID, p.S) == b.TotalPoints() {
 			p.S.G.Finished = true
@@ -238,6 +289,7 @@ func GiveNew(b *Bot, p CommandParameters) {
 		}
 	}
 	p.S.Users[p.UID] = U.AppendUnique(p.S.Users[p.UID], item)
+	p.S, _ = b.updateScore(p.UID, p.S)
 	msg := fmt.Sprintf("Gave you one `%s`", b.Items[item].Name)
 	if len(p.S.Users[p.UID]) == len(items) {
 		p.S.G.Finished = true
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedRNG is an RNG always rolling the same position in the requested range, from 0 (lowest) to 1
+// (highest).
+type fixedRNG float64
+
+func (r fixedRNG) Intn(n int) int {
+	res := int(float64(r) * float64(n))
+	if res >= n {
+		return n - 1
+	}
+	return res
+}
+
+func (r fixedRNG) Float64() float64     { return float64(r) }
+func (r fixedRNG) ExpFloat64() float64  { return 1 }
+func (r fixedRNG) NormFloat64() float64 { return 0 }
+
+func TestRolls(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	ghost := b.Monsters["1"]
+
+	t.Run("injected", func(t *testing.T) {
+		a.False(trickOrTreat(fixedRNG(0)))
+		a.True(trickOrTreat(fixedRNG(1)))
+		a.Equal("Candy", ghost.RandomItem(fixedRNG(0), b.Log).Name)
+		a.Equal("Skull", ghost.RandomItem(fixedRNG(1), b.Log).Name)
+
+		c := ChannelState{SpawnRate: 50, LastMessages: newHistoryAt(time.Now())}
+		c.LastMessages = c.LastMessages.Add(NewMessage("1", time.Now()))
+		a.True(c.Spawns(fixedRNG(0.49)))
+		a.False(c.Spawns(fixedRNG(0.5)))
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		rolls := func(rng U.RNG) []string {
+			res := []string{}
+			for i := 0; i < 20; i++ {
+				res = append(res, b.randomMonster(rng).Name, ghost.RandomItem(rng, b.Log).ID)
+				if trickOrTreat(rng) {
+					res = append(res, "treat")
+				}
+			}
+			return res
+		}
+		a.Equal(rolls(U.NewSeededRNG(7)), rolls(U.NewSeededRNG(7)))
+		a.NotEqual(rolls(U.NewSeededRNG(7)), rolls(U.NewSeededRNG(8)))
+	})
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/asdine/storm/v3"
+)
+
+// packHash identifies the monsters and items of the configuration, to only combine the scores of
+// servers whose collections were gathered with the same items.
+func packHash(items map[string]Item) string {
+	keys := []string{}
+	for id, item := range items {
+		keys = append(keys, fmt.Sprintf("%s:%d", id, item.Points))
+	}
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strings.Join(keys, ",")))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// GlobalServers returns the servers taking part in the global leaderboard with the current pack.
+func (b *Bot) GlobalServers() []Server {
+	var servers []Server
+	err := b.db.Find("Global", true, &servers)
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		b.ErrorE(err, "listing global servers")
+	}
+	res := []Server{}
+	for _, serv := range servers {
+		if serv.Pack == b.pack {
+			res = append(res, serv)
+		}
+	}
+	return res
+}
+
+// GlobalLeaderboard combines the stored leaderboards of the participating servers: the score of a
+// player is the sum of their scores in each server.
+func (b *Bot) GlobalLeaderboard() (Leaderboard, int) {
+	servers := b.GlobalServers()
+	scores := make(map[string]*ScoreBoard)
+	for _, serv := range servers {
+		for _, sb := range serv.Lb {
+			if serv.Hidden(sb.UID) {
+				continue
+			}
+			global, ok := scores[sb.UID]
+			if !ok {
+				global = &ScoreBoard{UID: sb.UID}
+				scores[sb.UID] = global
+			}
+			global.Score += sb.Score
+			if global.Name == "" {
+				global.Name = sb.Name
+			}
+		}
+	}
+	res := Leaderboard{}
+	for _, sb := range scores {
+		res = append(res, *sb)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].UID < res[j].UID
+	})
+	res.sort()
+	return res, len(servers)
+}
+
+const GlobalLeaderboardMenu = "glb"
+
+func (b *Bot) globalLeaderboardMenu(gID, cID string) *Menu[ScoreBoard] {
+	lb, servers := b.GlobalLeaderboard()
+	menu := b.rankingMenu(lb, rankings[RankingPoints], "Global leaderboard", cID, gID)
+	menu.SetSubtitle(fmt.Sprintf("Servers: `%d`\u2060 \u2060 \u2060 \u2060 \u2060 Players: `%d`", servers, len(lb)))
+	menu.SetKind(GlobalLeaderboardMenu)
+	return menu
+}
+
+func ShowGlobalLeaderboard(b *Bot, p CommandParameters) {
+	menu := b.globalLeaderboardMenu(p.GID, p.CID)
+	if !p.S.Global {
+		menu.SetFooter("This server does not take part in the global leaderboard.")
+	}
+	err := b.sendMenu(menu, p.I)
+	if err != nil {
+		p.Log.ErrorE(err, "creating menu")
+	}
+}
+
+func SetGlobal(b *Bot, p CommandParameters) {
+	arg := strings.ToLower(p.Options["state"].(string))
+	if arg != "on" && arg != "off" {
+		msg := fmt.Sprintf("usage: `%s%s <on|off>`", p.S.Prefix, p.Name)
+		SendText(b.s, p.I, p.CID, msg)
+		return
+	}
+
+	p.S.Global = arg == "on"
+	b.SaveServer(p.S)
+
+	msg := fmt.Sprintf("Global leaderboard participation set to `%s`", arg)
+	if p.S.Global && p.S.Pack != b.pack {
+		msg += fmt.Sprintf("\nThe collections of this server were gathered with another item pack: "+
+			"they only count after a `%sreset`.", p.S.Prefix)
+	}
+	SendText(b.s, p.I, p.CID, msg)
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobalLeaderboard(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+
+	save := func(id string, global bool, pack string, lb Leaderboard) {
+		serv := b.NewServer(id)
+		serv.Global = global
+		serv.Pack = pack
+		serv.Lb = lb
+		b.SaveServer(serv)
+	}
+	save("1", true, b.pack, Leaderboard{{UID: "a", Name: "alice", Score: 10}, {UID: "b", Name: "bob", Score: 6}})
+	save("2", true, b.pack, Leaderboard{{UID: "b", Name: "bobby", Score: 5}, {UID: "c", Name: "carol", Score: 1}})
+	save("3", false, b.pack, Leaderboard{{UID: "c", Name: "carol", Score: 100}})
+	save("4", true, "other", Leaderboard{{UID: "c", Name: "carol", Score: 100}})
+
+	lb, servers := b.GlobalLeaderboard()
+	a.Equal(2, servers)
+	a.Equal(Leaderboard{
+		{UID: "b", Name: "bob", Score: 11, Rank: "1st"},
+		{UID: "a", Name: "alice", Score: 10, Rank: "2nd"},
+		{UID: "c", Name: "carol", Score: 1, Rank: "3rd"},
+	}, lb)
+
+	t.Run("join", func(t *testing.T) {
+		a := assert.New(t)
+		p := CommandParameters{S: b.GetServer("4"), Options: map[string]interface{}{"state": "off"}}
+		SetGlobal(b, p)
+		p = CommandParameters{S: b.GetServer("4"), Options: map[string]interface{}{"state": "on"}}
+		SetGlobal(b, p)
+		a.True(b.GetServer("4").Global)
+		a.Equal("other", b.GetServer("4").Pack)
+		_, servers := b.GlobalLeaderboard()
+		a.Equal(2, servers)
+
+		p = CommandParameters{S: b.GetServer("3"), Options: map[string]interface{}{"state": "on"}}
+		SetGlobal(b, p)
+		a.True(b.GetServer("3").Global)
+		lb, servers := b.GlobalLeaderboard()
+		a.Equal(3, servers)
+		a.Equal("c", lb[0].UID)
+		a.Equal(101, lb[0].Score)
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		a := assert.New(t)
+		save("5", true, "", Leaderboard{{UID: "d", Name: "dave", Score: 2}})
+		a.NoError(b.migrate())
+		a.Equal(b.pack, b.GetServer("5").Pack)
+		a.Equal("other", b.GetServer("4").Pack)
+		_, servers := b.GlobalLeaderboard()
+		a.Equal(4, servers)
+
+		// Migrations only run once
+		save("6", true, "", nil)
+		a.NoError(b.migrate())
+		a.Empty(b.GetServer("6").Pack)
+	})
+}
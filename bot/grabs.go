@@ -0,0 +1,257 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	R "github.com/ashyaa/birtho/render"
+	U "github.com/ashyaa/birtho/util"
+)
+
+// GrabEvent records an item given to a player by a visitor.
+type GrabEvent struct {
+	ID       int    `storm:"id,increment"`
+	Guild    string `storm:"index"`
+	UID      string `storm:"index"`
+	Item     string
+	Points   int           // points gained, 0 if the player already had the item
+	Reaction time.Duration // delay between the visitor's arrival and the grab, 0 if unknown
+	Time     time.Time
+	Stamp    int64 `storm:"index"` // Time in Unix nanoseconds, indexed in order for the retention
+	// GuildStamp is the server followed by the stamp, indexed in order for the time windows
+	GuildStamp string `storm:"index"`
+}
+
+// guildStamp returns the key of the time in the index of the grabs of the server. Stamps are padded
+// to the width of the largest one, so that keys sort as times do.
+func guildStamp(gid string, stamp int64) string {
+	return fmt.Sprintf("%s@%019d", gid, stamp)
+}
+
+// saveGrab stores the grab event with its stamps.
+func (b *Bot) saveGrab(event *GrabEvent) error {
+	event.Stamp = event.Time.UnixNano()
+	event.GuildStamp = guildStamp(event.Guild, event.Stamp)
+	return b.db.Save(event)
+}
+
+func (b *Bot) recordGrab(gid, uid string, item Item, duplicate bool, spawnMID, grabMID string, log Logger) GrabEvent {
+	event := GrabEvent{Guild: gid, UID: uid, Item: item.ID, Time: time.Now()}
+	if !duplicate {
+		event.Points = item.Points
+	}
+	spawned, err1 := U.CreationTime(spawnMID)
+	grabbed, err2 := U.CreationTime(grabMID)
+	if err1 == nil && err2 == nil && grabbed.After(spawned) {
+		event.Reaction = grabbed.Sub(spawned)
+	}
+	if err := b.saveGrab(&event); err != nil {
+		log.ErrorE(err, "recording grab of %s", item.ID)
+	}
+	return event
+}
+
+// Grabs returns the grab events of the server since the given time, read from the index of the
+// server for all-time windows and from its time index otherwise.
+func (b *Bot) Grabs(gid string, since time.Time) []GrabEvent {
+	events := []GrabEvent{}
+	var err error
+	if since.IsZero() {
+		err = b.db.Find("Guild", gid, &events)
+	} else {
+		err = b.db.Range("GuildStamp", guildStamp(gid, since.UnixNano()), guildStamp(gid, math.MaxInt64), &events)
+	}
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		b.ErrorE(err, "listing grabs of server %s", gid)
+	}
+	return events
+}
+
+// pruneGrabs deletes the grab events older than the configured retention, then runs again a day
+// later. Grabs are kept forever without a retention.
+func (b *Bot) pruneGrabs() {
+	retention := b.conf.GrabRetention
+	if retention <= 0 {
+		return
+	}
+	count, err := b.deleteGrabs(time.Now().Add(-retention))
+	if err != nil {
+		b.ErrorE(err, "deleting old grabs")
+	} else {
+		b.Info("%d grabs older than %v deleted", count, retention)
+	}
+	b.afterFunc(24*time.Hour, b.pruneGrabs)
+}
+
+// deleteGrabs deletes the grab events before the given time. Returns the number of grabs deleted.
+func (b *Bot) deleteGrabs(before time.Time) (int, error) {
+	var events []GrabEvent
+	err := b.db.Range("Stamp", int64(0), before.UnixNano()-1, &events)
+	if errors.Is(err, storm.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	for i, event := range events {
+		if err := b.db.DeleteStruct(&event); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+// Leaderboard windows
+const (
+	WindowAll    = "all"
+	WindowToday  = "today"
+	WindowWeek   = "week"
+	WindowSeason = "season"
+)
+
+var windows = []string{WindowAll, WindowToday, WindowWeek, WindowSeason}
+
+// WindowStart returns the start of the leaderboard window. Windows never start before the last
+// reset, the grabs before it do not count anymore. Returns the zero time for all-time rankings of
+// servers never reset.
+func (s Server) WindowStart(window string, now time.Time) time.Time {
+	now = now.Local()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var res time.Time
+	switch window {
+	case WindowToday:
+		res = today
+	case WindowWeek:
+		sinceMonday := (int(today.Weekday()) + 6) % 7
+		res = today.AddDate(0, 0, -sinceMonday)
+	}
+	if res.Before(s.G.SeasonStart) {
+		return s.G.SeasonStart
+	}
+	return res
+}
+
+func windowLabel(window string) string {
+	switch window {
+	case WindowToday:
+		return " today"
+	case WindowWeek:
+		return " this week"
+	case WindowSeason:
+		return " this season"
+	}
+	return ""
+}
+
+// playerStats are the statistics of a player over the grab events of a window.
+type playerStats struct {
+	points, grabs, rares int
+	reaction             time.Duration
+	reactions            int
+}
+
+// Ranking is a way of ranking the players from their statistics.
+type Ranking struct {
+	Title     string
+	Column    string
+	Ascending bool                          // lower scores rank first
+	Format    func(score int) string        // formats the score, as a number if nil
+	score     func(playerStats) (int, bool) // score of the player, false if the player is unranked
+}
+
+// Leaderboard rankings
+const (
+	RankingPoints   = "points"
+	RankingGrabs    = "grabs"
+	RankingReaction = "reaction"
+	RankingRares    = "rares"
+)
+
+var rankingNames = []string{RankingPoints, RankingGrabs, RankingReaction, RankingRares}
+
+var rankings = map[string]Ranking{
+	RankingPoints: {
+		Title:  "Server leaderboard",
+		Column: "Points",
+		score: func(ps playerStats) (int, bool) {
+			return ps.points, true
+		},
+	},
+	RankingGrabs: {
+		Title:  "Most grabs",
+		Column: "Grabs",
+		score: func(ps playerStats) (int, bool) {
+			return ps.grabs, true
+		},
+	},
+	RankingReaction: {
+		Title:     "Fastest average reaction",
+		Column:    "Reaction",
+		Ascending: true,
+		Format: func(ms int) string {
+			return fmt.Sprintf("%.2fs", float64(ms)/1000)
+		},
+		score: func(ps playerStats) (int, bool) {
+			if ps.reactions == 0 {
+				return 0, false
+			}
+			return int((ps.reaction / time.Duration(ps.reactions)).Milliseconds()), true
+		},
+	},
+	RankingRares: {
+		Title:  "Most rare items",
+		Column: "Rares",
+		score: func(ps playerStats) (int, bool) {
+			return ps.rares, ps.rares > 0
+		},
+	},
+}
+
+func (r Ranking) format(score int) string {
+	if r.Format == nil {
+		return strconv.Itoa(score)
+	}
+	return r.Format(score)
+}
+
+// windowLeaderboard ranks the players of the server from the grabs recorded since the given time.
+func (b *Bot) windowLeaderboard(serv Server, since time.Time, ranking Ranking) Leaderboard {
+	stats := make(map[string]*playerStats)
+	for _, event := range b.Grabs(serv.ID, since) {
+		ps, ok := stats[event.UID]
+		if !ok {
+			ps = &playerStats{}
+			stats[event.UID] = ps
+		}
+		ps.grabs++
+		ps.points += event.Points
+		if item, ok := b.Items[event.Item]; ok && item.Rarity() == R.Rare {
+			ps.rares++
+		}
+		if event.Reaction > 0 {
+			ps.reaction += event.Reaction
+			ps.reactions++
+		}
+	}
+
+	names := make(map[string]string)
+	for _, sb := range b.getLeaderBoard(serv) {
+		names[sb.UID] = sb.Name
+	}
+	res := Leaderboard{}
+	for uid, ps := range stats {
+		if score, ok := ranking.score(*ps); ok {
+			res = append(res, ScoreBoard{UID: uid, Name: names[uid], Score: score})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].UID < res[j].UID
+	})
+	res.sortBy(ranking.Ascending)
+	return res
+}
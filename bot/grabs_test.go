@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowStart(t *testing.T) {
+	a := assert.New(t)
+	season := time.Date(2023, 10, 1, 12, 0, 0, 0, time.Local)
+	serv := Server{G: Game{SeasonStart: season}}
+	now := time.Date(2023, 10, 19, 15, 4, 5, 0, time.Local) // a Thursday
+
+	a.True(Server{}.WindowStart(WindowAll, now).IsZero())
+	a.Equal(season, serv.WindowStart(WindowAll, now))
+	a.Equal(time.Date(2023, 10, 19, 0, 0, 0, 0, time.Local), serv.WindowStart(WindowToday, now))
+	a.Equal(time.Date(2023, 10, 16, 0, 0, 0, 0, time.Local), serv.WindowStart(WindowWeek, now))
+	a.Equal(season, serv.WindowStart(WindowSeason, now))
+
+	// Windows start after the last reset
+	serv.G.SeasonStart = time.Date(2023, 10, 18, 12, 0, 0, 0, time.Local)
+	a.Equal(time.Date(2023, 10, 19, 0, 0, 0, 0, time.Local), serv.WindowStart(WindowToday, now))
+	a.Equal(serv.G.SeasonStart, serv.WindowStart(WindowWeek, now))
+}
+
+func TestWindowLeaderboard(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	serv := b.NewServer("guild")
+
+	record := func(uid, item string, points int, reaction time.Duration, at time.Time) {
+		event := GrabEvent{Guild: serv.ID, UID: uid, Item: item, Points: points, Reaction: reaction, Time: at}
+		a.NoError(b.saveGrab(&event))
+	}
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	record("a", "m1i3", 10, 3*time.Second, old)
+	record("a", "m1i1", 1, time.Second, now)
+	record("a", "m1i1", 0, 2*time.Second, now)
+	record("b", "m1i2", 5, 500*time.Millisecond, now)
+	record("b", "m1i3", 10, 0, now)
+	b.saveGrab(&GrabEvent{Guild: "other", UID: "c", Item: "m1i3", Points: 10, Time: now})
+
+	since := now.Add(-time.Hour)
+	ranked := func(ranking string, since time.Time) map[string]int {
+		res := map[string]int{}
+		for _, sb := range b.windowLeaderboard(serv, since, rankings[ranking]) {
+			res[sb.UID] = sb.Score
+		}
+		return res
+	}
+	a.Equal(map[string]int{"a": 1, "b": 15}, ranked(RankingPoints, since))
+	a.Equal(map[string]int{"a": 11, "b": 15}, ranked(RankingPoints, time.Time{}))
+	a.Equal(map[string]int{"a": 2, "b": 2}, ranked(RankingGrabs, since))
+	a.Equal(map[string]int{"b": 1}, ranked(RankingRares, since))
+	a.Equal(map[string]int{"a": 2000, "b": 500}, ranked(RankingReaction, time.Time{}))
+
+	lb := b.windowLeaderboard(serv, time.Time{}, rankings[RankingReaction])
+	a.Equal("b", lb[0].UID)
+	a.Equal("1st", lb[0].Rank)
+	a.Equal("0.50s", rankings[RankingReaction].format(lb[0].Score))
+
+	t.Run("retention", func(t *testing.T) {
+		a := assert.New(t)
+		count, err := b.deleteGrabs(now.Add(-time.Hour))
+		a.NoError(err)
+		a.Equal(1, count)
+		a.Len(b.Grabs(serv.ID, time.Time{}), 4)
+		a.Len(b.Grabs("other", time.Time{}), 1)
+	})
+
+	t.Run("migration", func(t *testing.T) {
+		a := assert.New(t)
+		legacy := GrabEvent{Guild: serv.ID, UID: "d", Item: "m1i1", Points: 1, Time: now}
+		a.NoError(b.db.Save(&legacy))
+		a.Len(b.Grabs(serv.ID, since), 4)
+		a.NoError(b.migrateGuildStamps())
+		a.Len(b.Grabs(serv.ID, since), 5)
+	})
+}
+
+func TestOptionalOptions(t *testing.T) {
+	a := assert.New(t)
+	opts := newTestBot(t, newFakeSession(0)).command("leaderboard").Options
+
+	p := CommandParameters{Options: map[string]interface{}{}}
+	a.NoError(p.ParseOptionsFromRaws([]string{}, opts))
+	a.Empty(p.Options)
+
+	a.NoError(p.ParseOptionsFromRaws([]string{"week"}, opts))
+	a.Equal(map[string]interface{}{"window": "week"}, p.Options)
+
+	a.Error(p.ParseOptionsFromRaws([]string{"month"}, opts))
+}
+
+func TestUserOption(t *testing.T) {
+	a := assert.New(t)
+	opts := newTestBot(t, newFakeSession(0)).command("addadmin").Options
+
+	for _, mention := range []string{"<@!951792639001366558>", "<@951792639001366558>"} {
+		p := CommandParameters{Options: map[string]interface{}{}}
+		a.NoError(p.ParseOptionsFromRaws([]string{mention}, opts))
+		a.Equal("951792639001366558", p.Options["user"])
+	}
+	p := CommandParameters{Options: map[string]interface{}{}}
+	a.Error(p.ParseOptionsFromRaws([]string{"<#951792639001366558>"}, opts))
+}
+
+func TestApplicationCommands(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	buildOptions(b)
+	cmds := map[string]int{}
+	for _, cmd := range b.applicationCommands() {
+		cmds[cmd.Name] = len(cmd.Options)
+	}
+	a.Equal(2, cmds["leaderboard"])
+	a.NotContains(cmds, "trick")
+}
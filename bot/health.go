@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// HeartbeatTimeout is how long the gateway may go without acknowledging a heartbeat before the
+// bot is considered unhealthy. Discord heartbeats every ~41 seconds, so this allows a few misses.
+const HeartbeatTimeout = 2 * time.Minute
+
+// DisconnectTimeout is how long the gateway session may stay disconnected, while discordgo
+// reconnects, before the bot is considered unhealthy.
+const DisconnectTimeout = 5 * time.Minute
+
+type HealthStatus struct {
+	Healthy          bool       `json:"healthy"`
+	Ready            bool       `json:"ready"`
+	Session          string     `json:"session"`
+	DisconnectedAt   *time.Time `json:"disconnected-at,omitempty"`
+	LastHeartbeatAck time.Time  `json:"last-heartbeat-ack"`
+	Database         string     `json:"database"`
+	Config           string     `json:"config"`
+	ConfigLoaded     time.Time  `json:"config-loaded"`
+	Monsters         int        `json:"monsters"`
+	Items            int        `json:"items"`
+}
+
+// Health reports the state of the gateway session, the database and the configuration.
+func (b *Bot) Health() HealthStatus {
+	res := HealthStatus{
+		Session:      "disconnected",
+		Database:     "ok",
+		Config:       "not loaded",
+		ConfigLoaded: b.confLoaded,
+		Monsters:     len(b.Monsters),
+		Items:        len(b.Items),
+	}
+
+	sessionReady := false
+	heartbeatOk := false
+	if b.ws != nil {
+		b.ws.RLock()
+		sessionReady = b.ws.DataReady
+		res.LastHeartbeatAck = b.ws.LastHeartbeatAck
+		b.ws.RUnlock()
+		heartbeatOk = time.Since(res.LastHeartbeatAck) < HeartbeatTimeout
+		res.DisconnectedAt = b.disconnectedAt(sessionReady)
+		if sessionReady {
+			res.Session = "connected"
+			if !heartbeatOk {
+				res.Session = "heartbeat timed out"
+			}
+		}
+	}
+
+	dbOk := true
+	if b.db == nil {
+		res.Database = "not opened"
+		dbOk = false
+	} else if _, err := b.db.Count(&Server{}); err != nil {
+		res.Database = err.Error()
+		dbOk = false
+	}
+
+	confOk := !b.confLoaded.IsZero() && len(b.Monsters) > 0
+	if confOk {
+		res.Config = "loaded from " + b.conf.filepath
+	}
+
+	sessionOk := heartbeatOk
+	if !sessionReady {
+		sessionOk = res.DisconnectedAt == nil || time.Since(*res.DisconnectedAt) < DisconnectTimeout
+	}
+	res.Healthy = dbOk && sessionOk
+	res.Ready = dbOk && confOk && sessionReady && heartbeatOk
+	return res
+}
+
+// disconnectedAt returns since when the gateway session is not ready, the first time it was seen
+// so, or nil if it is ready.
+func (b *Bot) disconnectedAt(ready bool) *time.Time {
+	b.healthMutex.Lock()
+	defer b.healthMutex.Unlock()
+	if ready {
+		b.notReadySince = time.Time{}
+		return nil
+	}
+	if b.notReadySince.IsZero() {
+		b.notReadySince = time.Now()
+	}
+	res := b.notReadySince
+	return &res
+}
+
+// HealthHandler serves the /healthz and /readyz endpoints. /healthz fails when the database is
+// unreachable, the gateway websocket stopped acknowledging heartbeats or stayed disconnected
+// longer than DisconnectTimeout, /readyz additionally
+// fails until the session is connected and the configuration is loaded.
+func (b *Bot) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		status := b.Health()
+		writeHealth(w, status, status.Healthy)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		status := b.Health()
+		writeHealth(w, status, status.Ready)
+	})
+	return mux
+}
+
+func writeHealth(w http.ResponseWriter, status HealthStatus, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// ServeHealth starts the health HTTP server if an address is configured.
+func (b *Bot) ServeHealth() {
+	if b.conf.HealthAddr == "" {
+		return
+	}
+	b.health = &http.Server{
+		Addr:              b.conf.HealthAddr,
+		Handler:           b.HealthHandler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		err := b.health.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			b.ErrorE(err, "health server")
+		}
+	}()
+	b.Info("health endpoints listening on %s", b.conf.HealthAddr)
+}
+
+func (b *Bot) StopHealth() {
+	if b.health == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.health.Shutdown(ctx); err != nil {
+		b.ErrorE(err, "stopping health server")
+	}
+}
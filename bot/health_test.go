@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	DG "github.com/bwmarrin/discordgo"
+	LR "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	a := assert.New(t)
+	db, err := storm.Open(filepath.Join(t.TempDir(), "app.db"))
+	a.NoError(err)
+	s, _ := DG.New("Bot token")
+	b := &Bot{
+		ws:         s,
+		db:         db,
+		Log:        LR.New(),
+		Monsters:   map[string]Monster{"1": {ID: 1}},
+		confLoaded: time.Now(),
+	}
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		b.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	t.Run("session not ready", func(t *testing.T) {
+		a.Equal(http.StatusOK, get("/healthz"))
+		a.Equal(http.StatusServiceUnavailable, get("/readyz"))
+	})
+	t.Run("ready", func(t *testing.T) {
+		s.DataReady = true
+		s.LastHeartbeatAck = time.Now()
+		a.Equal(http.StatusOK, get("/healthz"))
+		a.Equal(http.StatusOK, get("/readyz"))
+	})
+	t.Run("disconnected", func(t *testing.T) {
+		s.DataReady = false
+		a.Equal(http.StatusOK, get("/healthz"))
+		a.Equal(http.StatusServiceUnavailable, get("/readyz"))
+		b.notReadySince = time.Now().Add(-2 * DisconnectTimeout)
+		a.Equal(http.StatusServiceUnavailable, get("/healthz"))
+
+		s.DataReady = true
+		a.Equal(http.StatusOK, get("/healthz"))
+		a.True(b.notReadySince.IsZero())
+	})
+	t.Run("heartbeat timed out", func(t *testing.T) {
+		s.LastHeartbeatAck = time.Now().Add(-2 * HeartbeatTimeout)
+		a.Equal(http.StatusServiceUnavailable, get("/healthz"))
+		a.Equal(http.StatusServiceUnavailable, get("/readyz"))
+	})
+	t.Run("database closed", func(t *testing.T) {
+		s.LastHeartbeatAck = time.Now()
+		db.Close()
+		a.Equal(http.StatusServiceUnavailable, get("/healthz"))
+		a.Equal(http.StatusServiceUnavailable, get("/readyz"))
+	})
+}
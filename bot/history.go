@@ -12,6 +12,7 @@ type Message struct {
 	Time   time.Time
 	Author string
 	Valid  bool
+	Digest uint32 `json:",omitempty"` // hash of the content, to spot repeated messages
 }
 
 func NewMessage(author string, time time.Time) Message {
@@ -25,8 +26,12 @@ func NewMessage(author string, time time.Time) Message {
 type History []Message
 
 func NewHistory() History {
+	return newHistoryAt(time.Now())
+}
+
+// newHistoryAt returns a history of messages without author, all sent at now.
+func newHistoryAt(now time.Time) History {
 	res := make(History, HistoryDepth)
-	now := time.Now()
 	for i := 0; i < HistoryDepth; i++ {
 		res[i] = NewMessage("", now)
 	}
@@ -45,12 +50,26 @@ func IsHistoryInvalid(h History) bool {
 	return false
 }
 
-func (h History) Update(msg *DG.Message) History {
+// messageOf returns the history entry of a Discord message, sent when its ID was created.
+func messageOf(msg *DG.Message) (Message, error) {
 	msgTime, err := U.CreationTime(msg.ID)
+	if err != nil {
+		return Message{}, err
+	}
+	return NewMessage(msg.Author.ID, msgTime), nil
+}
+
+func (h History) Update(msg *DG.Message) History {
+	entry, err := messageOf(msg)
 	if err != nil {
 		return h
 	}
-	res := append(h, NewMessage(msg.Author.ID, msgTime))
+	return h.Add(entry)
+}
+
+// Add returns the history with msg as the latest message, dropping the oldest one.
+func (h History) Add(msg Message) History {
+	res := append(h, msg)
 	return res[1:]
 }
 
@@ -0,0 +1,238 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+)
+
+// Import modes
+const (
+	ImportMerge   = "merge"   // add the imported items to the players' collections
+	ImportReplace = "replace" // replace all the collections with the imported ones
+)
+
+var importModes = []string{ImportMerge, ImportReplace}
+
+// MaxImportSize bounds the size of an imported file.
+const MaxImportSize = 8 << 20
+
+// ParseExport decodes an export in JSON or CSV.
+func ParseExport(data []byte) (Export, error) {
+	var res Export
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err := json.Unmarshal(trimmed, &res)
+		return res, err
+	}
+	return parseCSVExport(data)
+}
+
+func parseCSVExport(data []byte) (Export, error) {
+	res := Export{Players: []ExportPlayer{}}
+	// Leading comments hold the guild, the pack and the settings
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "# ") {
+			break
+		}
+		key, value, _ := strings.Cut(strings.TrimPrefix(line, "# "), ": ")
+		switch key {
+		case "guild":
+			res.Guild = value
+		case "exported":
+			res.Exported, _ = time.Parse(time.RFC3339, value)
+		case "pack":
+			res.Pack = value
+		case "settings":
+			if err := json.Unmarshal([]byte(value), &res.Settings); err != nil {
+				return res, fmt.Errorf("invalid settings: %w", err)
+			}
+		}
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = 9
+	records, err := r.ReadAll()
+	if err != nil {
+		return res, err
+	}
+	if len(records) == 0 || records[0][1] != "uid" || records[0][4] != "item" {
+		return res, errors.New("missing CSV header")
+	}
+	players := make(map[string]int)
+	for _, record := range records[1:] {
+		uid, item := record[1], record[4]
+		i, ok := players[uid]
+		if !ok {
+			i = len(res.Players)
+			players[uid] = i
+			res.Players = append(res.Players, ExportPlayer{Rank: record[0], UID: uid, Name: csvUnescape(record[2])})
+		}
+		if item != "" {
+			res.Players[i].Items = append(res.Players[i].Items, ExportItem{ID: item, Name: csvUnescape(record[5])})
+		}
+	}
+	return res, nil
+}
+
+// ImportReport describes the result of an import.
+type ImportReport struct {
+	Mode    string
+	Players int
+	Items   int
+	Skipped int      // players excluded from the game or who erased their data
+	Unknown []string // IDs of the items not in the current pack, which were skipped
+}
+
+func (r ImportReport) String() string {
+	res := fmt.Sprintf("Imported %d items of %d players (%s).", r.Items, r.Players, r.Mode)
+	if r.Skipped > 0 {
+		res += fmt.Sprintf("\nSkipped %d players banned, opted out or who erased their data.", r.Skipped)
+	}
+	if len(r.Unknown) > 0 {
+		res += fmt.Sprintf("\nUnknown items skipped: `%s`", strings.Join(r.Unknown, "`, `"))
+	}
+	return res
+}
+
+// Import loads the collections of an export into the server, skipping the items unknown to the
+// current pack and the players excluded from the game or who erased their data since the export.
+// It rebuilds the leaderboard and checks the winner of the game.
+func (b *Bot) Import(serv Server, e Export, mode string) (Server, ImportReport, error) {
+	report := ImportReport{Mode: mode}
+	if mode != ImportMerge && mode != ImportReplace {
+		return serv, report, fmt.Errorf("unknown import mode %s", mode)
+	}
+	names := make(map[string]string)
+	if mode == ImportReplace {
+		serv.Users = make(map[string][]string)
+		serv.Pity = make(map[string]PityCounter)
+	}
+
+	unknown := make(map[string]bool)
+	for _, player := range e.Players {
+		if player.UID == "" {
+			continue
+		}
+		if serv.Excluded(player.UID) || IsPseudonym(player.UID) || b.forgottenSince(player.UID, e.Exported) {
+			report.Skipped++
+			continue
+		}
+		report.Players++
+		names[player.UID] = player.Name
+		if _, ok := serv.Users[player.UID]; !ok {
+			serv.Users[player.UID] = make([]string, 0)
+		}
+		for _, item := range player.Items {
+			if _, ok := b.Items[item.ID]; !ok {
+				unknown[item.ID] = true
+				continue
+			}
+			report.Items++
+			serv.Users[player.UID] = U.AppendUnique(serv.Users[player.UID], item.ID)
+		}
+	}
+	for id := range unknown {
+		report.Unknown = append(report.Unknown, id)
+	}
+	sort.Strings(report.Unknown)
+
+	// Imported names are used for players the server does not know yet
+	serv = b.recomputeWinner(b.RecomputeLeaderboard(serv))
+	for i, sb := range serv.Lb {
+		if sb.Name == "" {
+			serv.Lb[i].Name = names[sb.UID]
+		}
+	}
+	// A merge keeps the items gathered with the previous pack
+	if mode == ImportReplace {
+		serv.Pack = b.pack
+	}
+	return serv, report, nil
+}
+
+// downloadAttachment returns the content of a file attached to a message.
+func downloadAttachment(url string) ([]byte, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxImportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxImportSize {
+		return nil, fmt.Errorf("file larger than %d bytes", MaxImportSize)
+	}
+	return data, nil
+}
+
+func ImportData(b *Bot, p CommandParameters) {
+	mode, ok := p.Options["mode"].(string)
+	if !ok {
+		mode = ImportMerge
+	}
+	data, err := downloadAttachment(p.Options["file"].(string))
+	if err != nil {
+		p.Log.WarnE(err, "downloading import")
+		SendText(b.s, p.I, p.CID, "Could not download the file: "+err.Error())
+		return
+	}
+	e, err := ParseExport(data)
+	if err != nil {
+		SendText(b.s, p.I, p.CID, "Invalid export file: "+err.Error())
+		return
+	}
+	if e.Guild != "" && e.Guild != p.GID {
+		msg := fmt.Sprintf("This file is the export of another server (`%s`), use `birtho import --guild %s` to import it here.", e.Guild, p.GID)
+		SendText(b.s, p.I, p.CID, msg)
+		return
+	}
+	// The server is only locked once the file is downloaded
+	defer b.lockGuild(p.GID)()
+	serv, report, err := b.Import(b.GetServer(p.GID), e, mode)
+	if err != nil {
+		SendText(b.s, p.I, p.CID, err.Error())
+		return
+	}
+	b.SaveServer(serv)
+	p.Log.Info("imported %d items of %d players (%s), %d unknown items", report.Items, report.Players, mode, len(report.Unknown))
+	SendText(b.s, p.I, p.CID, report.String())
+}
+
+// ImportGuild imports an encoded export into a stored server, creating it if needed. If gid is
+// empty, the export is imported into the server it was exported from.
+func (b *Bot) ImportGuild(gid string, data []byte, mode string) (ImportReport, error) {
+	e, err := ParseExport(data)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	if gid == "" {
+		gid = e.Guild
+	}
+	if gid == "" {
+		return ImportReport{}, errors.New("the export does not name its server, set the server ID")
+	}
+	serv, report, err := b.Import(b.GetServer(gid), e, mode)
+	if err != nil {
+		return report, err
+	}
+	return report, b.db.Save(&serv)
+}
@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	DG "github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImport(t *testing.T) {
+	b := newTestBot(t, newFakeSession(0))
+	source := b.NewServer("source")
+	source.Users["a"] = []string{"m1i1", "m1i3"}
+	source.Users["b"] = []string{"m1i2"}
+	source.Lb = Leaderboard{{UID: "a", Name: "alice"}, {UID: "b", Name: "bob"}}
+	export := b.Export(source)
+	export.Players[1].Items = append(export.Players[1].Items, ExportItem{ID: "m9i9"})
+
+	for _, format := range exportFormats {
+		t.Run(format, func(t *testing.T) {
+			a := assert.New(t)
+			data, err := export.Encode(format)
+			a.NoError(err)
+			parsed, err := ParseExport(data)
+			a.NoError(err)
+			a.Equal("source", parsed.Guild)
+
+			target := b.NewServer("target")
+			target.Users["a"] = []string{"m1i2"}
+			target.Users["c"] = []string{"m1i1"}
+			target.Pack = "old"
+
+			merged, report, err := b.Import(target, parsed, ImportMerge)
+			a.NoError(err)
+			a.Equal("old", merged.Pack)
+			a.Equal(ImportReport{Mode: ImportMerge, Players: 2, Items: 3, Unknown: []string{"m9i9"}}, report)
+			a.ElementsMatch([]string{"m1i2", "m1i1", "m1i3"}, merged.Users["a"])
+			a.Equal([]string{"m1i1"}, merged.Users["c"])
+			a.Equal(ScoreBoard{UID: "a", Name: "alice", Score: 16, Rank: "1st"}, merged.Lb[0])
+
+			replaced, _, err := b.Import(target, parsed, ImportReplace)
+			a.NoError(err)
+			a.Equal(b.pack, replaced.Pack)
+			a.Equal(map[string][]string{"a": {"m1i1", "m1i3"}, "b": {"m1i2"}}, replaced.Users)
+		})
+	}
+
+	t.Run("players", func(t *testing.T) {
+		a := assert.New(t)
+		all := []string{}
+		for id := range b.Items {
+			all = append(all, id)
+		}
+		source := b.NewServer("source")
+		for _, uid := range []string{"banned", "opted", "forgotten", "forgotten-0123", "winner"} {
+			source.Users[uid] = all
+		}
+		export := b.Export(source)
+		_, err := b.Forget("forgotten")
+		a.NoError(err)
+
+		target := b.NewServer("target")
+		target.Banned = map[string]Ban{"banned": {By: "admin"}}
+		target.OptedOut = []string{"opted"}
+		target.Pity = map[string]PityCounter{"old": {Rare: 3}}
+		target.G.Finished = true
+		target.G.Winner = "old"
+		target.Users["old"] = all
+
+		merged, report, err := b.Import(target, export, ImportMerge)
+		a.NoError(err)
+		a.Equal(1, report.Players)
+		a.Equal(4, report.Skipped)
+		a.Len(merged.Users, 2)
+		a.Contains(merged.Users, "winner")
+		a.Equal("old", merged.G.Winner)
+
+		replaced, _, err := b.Import(target, export, ImportReplace)
+		a.NoError(err)
+		a.Len(replaced.Users, 1)
+		a.Contains(replaced.Users, "winner")
+		a.Empty(replaced.Pity)
+		a.True(replaced.G.Finished)
+		a.Equal("winner", replaced.G.Winner)
+
+		export.Players = nil
+		replaced, _, err = b.Import(target, export, ImportReplace)
+		a.NoError(err)
+		a.False(replaced.G.Finished)
+		a.Empty(replaced.G.Winner)
+
+		// An export made after the player erased their data can bring their new items
+		export = b.Export(source)
+		_, report, err = b.Import(target, export, ImportMerge)
+		a.NoError(err)
+		a.Equal(2, report.Players)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		a := assert.New(t)
+		_, err := ParseExport([]byte("lorem,ipsum"))
+		a.Error(err)
+		_, _, err = b.Import(source, export, "append")
+		a.Error(err)
+	})
+}
+
+func TestImportCommand(t *testing.T) {
+	a := assert.New(t)
+	s := newFakeSession(0)
+	b := newTestBot(t, s)
+	source := b.NewServer("guild")
+	source.Users["a"] = []string{"m1i3"}
+	data, err := b.Export(source).Encode(FormatCSV)
+	a.NoError(err)
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The guild is not locked during the download
+		unlocked := make(chan struct{})
+		go func() {
+			b.lockGuild("guild")()
+			close(unlocked)
+		}()
+		select {
+		case <-unlocked:
+		case <-time.After(time.Second):
+			t.Error("guild locked during the download")
+		}
+		w.Write(data)
+	}))
+	defer files.Close()
+
+	m := messageCreate("guild", "channel", "admin", "b!import replace")
+	m.Attachments = []*DG.MessageAttachment{{URL: files.URL}}
+	HandlerFromMessageCreate(b, b.command("import"))(nil, m)
+	a.Equal([]string{"m1i3"}, b.GetServer("guild").Users["a"])
+	a.Equal(10, b.GetServer("guild").Lb[0].Score)
+
+	other := messageCreate("other", "channel", "admin", "b!import")
+	other.Attachments = m.Attachments
+	HandlerFromMessageCreate(b, b.command("import"))(nil, other)
+	a.Empty(b.GetServer("other").Users)
+}
@@ -0,0 +1,34 @@
+package bot
+
+import "sync"
+
+// guildLock returns the lock of the server state of the guild gid.
+func (b *Bot) guildLock(gid string) *sync.Mutex {
+	b.guildLocksMutex.Lock()
+	defer b.guildLocksMutex.Unlock()
+	lock, ok := b.guildLocks[gid]
+	if !ok {
+		lock = &sync.Mutex{}
+		b.guildLocks[gid] = lock
+	}
+	return lock
+}
+
+// lockGuild locks the server state of the guild gid, so commands modifying a server do not
+// overwrite each other while commands of other guilds progress independently. It returns the
+// function unlocking the guild.
+func (b *Bot) lockGuild(gid string) func() {
+	lock := b.guildLock(gid)
+	lock.Lock()
+	return lock.Unlock
+}
+
+// tryLockGuild locks the guild like lockGuild, unless a command already holds the lock, in which
+// case it returns false.
+func (b *Bot) tryLockGuild(gid string) (func(), bool) {
+	lock := b.guildLock(gid)
+	if !lock.TryLock() {
+		return nil, false
+	}
+	return lock.Unlock, true
+}
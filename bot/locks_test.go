@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Concurrent commands of a guild must not overwrite each other's server updates.
+func TestGuildLockSerializesUpdates(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(time.Millisecond))
+	handler := HandlerFromMessageCreate(b, b.command("give"))
+
+	const nbUsers = 20
+	wg := sync.WaitGroup{}
+	for i := 0; i < nbUsers; i++ {
+		wg.Add(1)
+		go func(uid string) {
+			defer wg.Done()
+			handler(nil, messageCreate("guild", "channel", uid, DefaultPrefix+"give"))
+		}(fmt.Sprintf("user%d", i))
+	}
+	wg.Wait()
+
+	serv := b.GetServer("guild")
+	a.Len(serv.Users, nbUsers)
+	for uid, items := range serv.Users {
+		a.Len(items, 1, uid)
+	}
+}
+
+// Load test: spawns in many guilds must progress independently of each other, so the total time
+// is close to the time needed by a single guild rather than the sum over all guilds.
+func TestGuildThroughput(t *testing.T) {
+	a := assert.New(t)
+	const (
+		nbGuilds         = 50
+		messagesPerGuild = 4
+		latency          = 20 * time.Millisecond
+	)
+	s := newFakeSession(latency)
+	b := newTestBot(t, s)
+	handler := HandlerFromMessageCreate(b, b.command("spawn"))
+
+	start := time.Now()
+	wg := sync.WaitGroup{}
+	for g := 0; g < nbGuilds; g++ {
+		for m := 0; m < messagesPerGuild; m++ {
+			wg.Add(1)
+			go func(gid, cid string) {
+				defer wg.Done()
+				handler(nil, messageCreate(gid, cid, "user", DefaultPrefix+"spawn"))
+			}(fmt.Sprintf("guild%d", g), fmt.Sprintf("channel%d", m))
+		}
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	total := nbGuilds * messagesPerGuild
+	serialized := time.Duration(s.calls.Load()) * latency
+	t.Logf("%d spawns in %d guilds: %v (%.0f spawns/s), %v if serialized",
+		total, nbGuilds, elapsed, float64(total)/elapsed.Seconds(), serialized)
+	a.EqualValues(total, s.calls.Load())
+	a.Less(elapsed, serialized/5)
+	for g := 0; g < nbGuilds; g++ {
+		a.Len(b.GetServer(fmt.Sprintf("guild%d", g)).G.Monsters, messagesPerGuild)
+	}
+}
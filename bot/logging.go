@@ -1,5 +1,7 @@
 package bot
 
+import LR "github.com/sirupsen/logrus"
+
 func (b *Bot) Debug(format string, args ...interface{}) {
 	b.Log.Debugf(format, args...)
 }
@@ -43,3 +45,63 @@ func (b *Bot) FatalE(err error, format string, args ...interface{}) {
 	actualArgs := append(args, err.Error())
 	b.Log.Fatalf(actualFormat, actualArgs...)
 }
+
+// Structured log fields attached to handler log lines
+const (
+	FieldGuild   = "guild"
+	FieldChannel = "channel"
+	FieldUser    = "user"
+	FieldCommand = "command"
+)
+
+// Logger provides the same printf-style helpers as the bot, on a log entry carrying structured
+// fields.
+type Logger struct {
+	e *LR.Entry
+}
+
+func (b *Bot) WithFields(fields LR.Fields) Logger {
+	return Logger{b.Log.WithFields(fields)}
+}
+
+// CommandLogger returns a logger attaching the guild, channel, user and command name to every line.
+func (b *Bot) CommandLogger(gid, cid, uid, name string) Logger {
+	return b.WithFields(LR.Fields{
+		FieldGuild:   gid,
+		FieldChannel: cid,
+		FieldUser:    uid,
+		FieldCommand: name,
+	})
+}
+
+func (l Logger) With(key string, value interface{}) Logger {
+	return Logger{l.e.WithField(key, value)}
+}
+
+func (l Logger) Debug(format string, args ...interface{}) {
+	l.e.Debugf(format, args...)
+}
+
+func (l Logger) DebugE(err error, format string, args ...interface{}) {
+	l.e.WithError(err).Debugf(format, args...)
+}
+
+func (l Logger) Warn(format string, args ...interface{}) {
+	l.e.Warnf(format, args...)
+}
+
+func (l Logger) WarnE(err error, format string, args ...interface{}) {
+	l.e.WithError(err).Warnf(format, args...)
+}
+
+func (l Logger) Error(format string, args ...interface{}) {
+	l.e.Errorf(format, args...)
+}
+
+func (l Logger) ErrorE(err error, format string, args ...interface{}) {
+	l.e.WithError(err).Errorf(format, args...)
+}
+
+func (l Logger) Info(format string, args ...interface{}) {
+	l.e.Infof(format, args...)
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	U "github.com/ashyaa/birtho/util"
+)
+
+// DefaultDuplicateWeight is the weight factor of the owned items with the bad luck protection on.
+const DefaultDuplicateWeight = 0.25
+
+// LuckOptions set the bad luck protection, which makes monsters less likely to give the items the
+// grabbing player already owns.
+type LuckOptions struct {
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// DuplicateWeight multiplies the chance of the owned items, DefaultDuplicateWeight if not in (0, 1]
+	DuplicateWeight float64 `json:"duplicate-weight,omitempty" yaml:"duplicate-weight,omitempty"`
+}
+
+func (o LuckOptions) duplicateWeight() float64 {
+	if o.DuplicateWeight <= 0 || o.DuplicateWeight > 1 {
+		return DefaultDuplicateWeight
+	}
+	return o.DuplicateWeight
+}
+
+// itemWeights returns the chances of the items of the monster, the ones in owned being multiplied
+// by duplicateWeight.
+func (m Monster) itemWeights(owned []string, duplicateWeight float64) []float64 {
+	res := make([]float64, len(m.Items))
+	for i, item := range m.Items {
+		res[i] = item.Chance
+		if m.EqualItemChances {
+			res[i] = 1
+		}
+		if U.Contains(owned, item.ID) {
+			res[i] *= duplicateWeight
+		}
+	}
+	return res
+}
+
+// RandomItemFor rolls an item of the monster for a player owning the given items, with the owned
+// items weighted down by duplicateWeight.
+func (m Monster) RandomItemFor(rng U.RNG, owned []string, duplicateWeight float64) Item {
+	weights := m.itemWeights(owned, duplicateWeight)
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	roll := rng.Float64() * total
+	for i, w := range weights {
+		if roll < w {
+			return m.Items[i]
+		}
+		roll -= w
+	}
+	return m.Items[len(m.Items)-1] // rounding errors
+}
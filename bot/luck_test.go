@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// meanAndError returns the mean of durations, in hours, and its standard error.
+func meanAndError(durations []time.Duration) (float64, float64) {
+	mean, squares := 0.0, 0.0
+	for _, d := range durations {
+		mean += d.Hours()
+	}
+	mean /= float64(len(durations))
+	for _, d := range durations {
+		squares += (d.Hours() - mean) * (d.Hours() - mean)
+	}
+	variance := squares / float64(len(durations)-1)
+	return mean, math.Sqrt(variance / float64(len(durations)))
+}
+
+func TestBadLuckProtection(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	ghost := b.Monsters["1"]
+	candy := ghost.Items[0]
+
+	frequencies := func(owned []string, weight float64) map[string]float64 {
+		rng := U.NewSeededRNG(3)
+		res := make(map[string]float64)
+		const rolls = 50000
+		for i := 0; i < rolls; i++ {
+			res[ghost.RandomItemFor(rng, owned, weight).ID] += 1.0 / rolls
+		}
+		return res
+	}
+
+	t.Run("nothing owned", func(t *testing.T) {
+		got := frequencies(nil, 0.5)
+		for _, item := range ghost.Items {
+			a.InDelta(item.Chance/100, got[item.ID], 0.01, item.Name)
+		}
+	})
+
+	t.Run("duplicates weighted down", func(t *testing.T) {
+		got := frequencies([]string{candy.ID}, 0.5)
+		// 50*0.5 / (50*0.5 + 35 + 15)
+		a.InDelta(1.0/3, got[candy.ID], 0.01)
+		a.InDelta(35.0/75, got[ghost.Items[1].ID], 0.01)
+		a.InDelta(15.0/75, got[ghost.Items[2].ID], 0.01)
+	})
+
+	t.Run("collection time", func(t *testing.T) {
+		sim := DefaultSimulation()
+		sim.Runs = 200
+		sim.Players = 5
+		sim.MessagesPerHour = 20
+		sim.Duration = 30 * 24 * time.Hour
+		sim.Seed = 11
+		plain, err := b.simulate(sim)
+		a.NoError(err)
+		b.conf.BadLuck = LuckOptions{Enabled: true}
+		defer func() { b.conf.BadLuck = LuckOptions{} }()
+		protected, err := b.simulate(sim)
+		a.NoError(err)
+
+		a.Equal(sim.Runs, len(plain.Completions))
+		a.Equal(sim.Runs, len(protected.Completions))
+		plainMean, plainError := meanAndError(plain.Completions)
+		protectedMean, protectedError := meanAndError(protected.Completions)
+		t.Logf("time to the first complete collection: %.1fh±%.1f without protection, %.1fh±%.1f with",
+			plainMean, plainError, protectedMean, protectedError)
+		// The difference is significant, beyond three standard errors
+		a.Greater(plainMean-protectedMean, 3*math.Sqrt(plainError*plainError+protectedError*protectedError))
+		a.Less(protected.Duplicates, plain.Duplicates)
+	})
+}
@@ -0,0 +1,189 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	U "github.com/ashyaa/birtho/util"
+)
+
+// Maintenance operations of the offline commands, working on stored servers without Discord.
+
+// Servers returns all the stored servers.
+func (b *Bot) Servers() ([]Server, error) {
+	var res []Server
+	err := b.db.All(&res)
+	if errors.Is(err, storm.ErrNotFound) {
+		return []Server{}, nil
+	}
+	return res, err
+}
+
+// Settings that can be set offline
+var settingNames = []string{"prefix", "play", "min-cooldown", "max-cooldown", "stay", "global", "policy"}
+
+// SetSetting changes a game setting of the server. Durations are given in Go format, eg "2m".
+func SetSetting(serv Server, key, value string) (Server, error) {
+	parseBool := func() (bool, error) {
+		switch strings.ToLower(value) {
+		case "on", "true":
+			return true, nil
+		case "off", "false":
+			return false, nil
+		}
+		return false, fmt.Errorf("invalid %s `%s`, expected on or off", key, value)
+	}
+	maxDelay := serv.G.MinDelay + time.Duration(serv.G.VariableDelay-1)*time.Second
+
+	var err error
+	switch key {
+	case "prefix":
+		if value == "" {
+			return serv, errors.New("empty prefix")
+		}
+		serv.Prefix = value
+	case "play":
+		serv.G.On, err = parseBool()
+	case "global":
+		serv.Global, err = parseBool()
+	case "policy":
+		if !U.Contains(policyNames, value) {
+			return serv, fmt.Errorf("unknown policy %s, expected one of: %s", value, strings.Join(policyNames, ", "))
+		}
+		serv.G.SpawnPolicy = value
+	case "min-cooldown", "max-cooldown", "stay":
+		var d time.Duration
+		d, err = time.ParseDuration(value)
+		if err != nil {
+			return serv, err
+		}
+		if d < 0 || (key == "stay" && d == 0) {
+			return serv, fmt.Errorf("invalid %s %v", key, d)
+		}
+		switch key {
+		case "min-cooldown":
+			if d > maxDelay {
+				return serv, fmt.Errorf("minimum cooldown %v is superior to the maximum %v", d, maxDelay)
+			}
+			serv.G.MinDelay = d
+		case "max-cooldown":
+			if d < serv.G.MinDelay {
+				return serv, fmt.Errorf("maximum cooldown %v is inferior to the minimum %v", d, serv.G.MinDelay)
+			}
+			maxDelay = d
+		case "stay":
+			serv.G.StayTime = d
+		}
+		serv.G.VariableDelay = int((maxDelay-serv.G.MinDelay)/time.Second) + 1
+	default:
+		return serv, fmt.Errorf("unknown setting %s, expected one of: %s", key, strings.Join(settingNames, ", "))
+	}
+	return serv, err
+}
+
+// GrantItem gives an item of the current pack to a player.
+func (b *Bot) GrantItem(serv Server, uid, itemID string) (Server, error) {
+	if _, ok := b.Items[itemID]; !ok {
+		return serv, fmt.Errorf("unknown item %s", itemID)
+	}
+	if U.Contains(serv.Users[uid], itemID) {
+		return serv, fmt.Errorf("%s already has item %s", uid, itemID)
+	}
+	serv.Users[uid] = append(serv.Users[uid], itemID)
+	return b.RecomputeLeaderboard(serv), nil
+}
+
+// RemoveItem takes an item back from a player.
+func (b *Bot) RemoveItem(serv Server, uid, itemID string) (Server, error) {
+	if !U.Contains(serv.Users[uid], itemID) {
+		return serv, fmt.Errorf("%s does not have item %s", uid, itemID)
+	}
+	items := []string{}
+	for _, item := range serv.Users[uid] {
+		if item != itemID {
+			items = append(items, item)
+		}
+	}
+	serv.Users[uid] = items
+	if serv.G.Finished && serv.G.Winner == uid {
+		serv.G.Finished = false
+		serv.G.Winner = ""
+	}
+	return b.RecomputeLeaderboard(serv), nil
+}
+
+// RemovePlayer takes all the items of a player back and removes them from the leaderboard.
+func (b *Bot) RemovePlayer(serv Server, uid string) Server {
+	delete(serv.Users, uid)
+	delete(serv.Pity, uid)
+	if serv.G.Finished && serv.G.Winner == uid {
+		serv.G.Finished = false
+		serv.G.Winner = ""
+	}
+	return b.RecomputeLeaderboard(serv)
+}
+
+// RecomputeLeaderboard rebuilds the leaderboard of the server from the players' collections,
+// keeping the known names.
+func (b *Bot) RecomputeLeaderboard(serv Server) Server {
+	names := make(map[string]string)
+	for _, sb := range serv.Lb {
+		names[sb.UID] = sb.Name
+	}
+	lb := Leaderboard{}
+	for uid := range serv.Users {
+		lb = append(lb, ScoreBoard{UID: uid, Name: names[uid], Score: b.GetUserScore(uid, serv)})
+	}
+	sort.Slice(lb, func(i, j int) bool {
+		return lb[i].UID < lb[j].UID
+	})
+	lb.sort()
+	serv.Lb = lb
+	b.dropRankIndex(serv.ID)
+	return serv
+}
+
+// recomputeWinner checks that the winner of the game still owns the whole collection, or else names
+// the first player of the leaderboard who does.
+func (b *Bot) recomputeWinner(serv Server) Server {
+	total := b.TotalPoints()
+	if serv.G.Finished && !serv.Excluded(serv.G.Winner) && b.GetUserScore(serv.G.Winner, serv) == total {
+		return serv
+	}
+	serv.G.Finished = false
+	serv.G.Winner = ""
+	for _, sb := range serv.Lb {
+		if sb.Score == total && !serv.Excluded(sb.UID) {
+			serv.G.Finished = true
+			serv.G.Winner = sb.UID
+			break
+		}
+	}
+	return serv
+}
+
+// ClearSpawns removes the visitors stuck in the server, eg after a crash. Returns the number of
+// visitors removed.
+func ClearSpawns(serv Server) (Server, int) {
+	count := len(serv.G.Monsters)
+	serv.G.Monsters = make(map[string]MonsterSpawn)
+	return serv, count
+}
+
+// ServerSummary is a line of the list of servers.
+func (b *Bot) ServerSummary(serv Server) string {
+	status := "off"
+	if serv.G.On {
+		status = "on"
+	}
+	if serv.G.Finished {
+		status += ", finished"
+	}
+	return fmt.Sprintf("%s\tplay %s\t%d players\t%d channels\t%d visitors\tprefix %s",
+		serv.ID, status, len(serv.Users), len(serv.Channels), len(serv.G.Monsters), strconv.Quote(serv.Prefix))
+}
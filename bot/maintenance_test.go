@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSetting(t *testing.T) {
+	a := assert.New(t)
+	serv := Server{Prefix: "b!", G: Game{MinDelay: time.Minute, VariableDelay: 61}}
+
+	serv, err := SetSetting(serv, "max-cooldown", "5m")
+	a.NoError(err)
+	a.Equal(241, serv.G.VariableDelay)
+	serv, err = SetSetting(serv, "min-cooldown", "2m")
+	a.NoError(err)
+	a.Equal(2*time.Minute, serv.G.MinDelay)
+	a.Equal(181, serv.G.VariableDelay) // the maximum cooldown is kept
+	serv, err = SetSetting(serv, "play", "on")
+	a.NoError(err)
+	a.True(serv.G.On)
+	serv, err = SetSetting(serv, "prefix", "a!")
+	a.NoError(err)
+	a.Equal("a!", serv.Prefix)
+
+	for _, invalid := range [][2]string{
+		{"min-cooldown", "10m"},
+		{"max-cooldown", "1m"},
+		{"stay", "0s"},
+		{"play", "maybe"},
+		{"lorem", "ipsum"},
+	} {
+		_, err = SetSetting(serv, invalid[0], invalid[1])
+		a.Error(err, invalid[0])
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	serv := b.NewServer("guild")
+	serv.Users["a"] = []string{"m1i1", "m1i2"}
+	serv.G.Finished = true
+	serv.G.Winner = "a"
+	serv.Lb = Leaderboard{{UID: "a", Name: "alice", Score: 1000, Rank: "1st"}}
+
+	serv = b.RecomputeLeaderboard(serv)
+	a.Equal(Leaderboard{{UID: "a", Name: "alice", Score: 6, Rank: "1st"}}, serv.Lb)
+
+	serv, err := b.GrantItem(serv, "b", "m1i3")
+	a.NoError(err)
+	a.Equal(ScoreBoard{UID: "b", Score: 10, Rank: "1st"}, serv.Lb[0])
+	_, err = b.GrantItem(serv, "b", "m1i3")
+	a.Error(err)
+	_, err = b.GrantItem(serv, "b", "m9i9")
+	a.Error(err)
+
+	serv, err = b.RemoveItem(serv, "a", "m1i2")
+	a.NoError(err)
+	a.Equal([]string{"m1i1"}, serv.Users["a"])
+	a.False(serv.G.Finished)
+	_, err = b.RemoveItem(serv, "a", "m1i2")
+	a.Error(err)
+
+	serv.G.Monsters["channel"] = MonsterSpawn{ID: "1"}
+	serv, count := ClearSpawns(serv)
+	a.Equal(1, count)
+	a.Empty(serv.G.Monsters)
+}
+
+func TestMigrateMinDelay(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	a.Equal(2*time.Minute, b.NewServer("new").G.MinDelay)
+
+	legacy := b.NewServer("legacy")
+	legacy.G.MinDelay = legacyMinDelay
+	b.SaveServer(legacy)
+	custom := b.NewServer("custom")
+	custom.G.MinDelay = time.Minute
+	b.SaveServer(custom)
+	a.NoError(b.migrateMinDelay())
+	a.Equal(DefaultMinDelay, b.GetServer("legacy").G.MinDelay)
+	a.Equal(time.Minute, b.GetServer("custom").G.MinDelay)
+}
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"sync"
+
+	U "github.com/ashyaa/birtho/util"
+	DG "github.com/bwmarrin/discordgo"
+)
+
+// MembersPageSize is the maximum number of members Discord returns per request.
+const MembersPageSize = 1000
+
+// memberCache keeps the display names of guild members. A guild is fully loaded with paginated
+// member requests the first time its leaderboard is named, then kept up to date by gateway
+// member events. It is safe for concurrent use.
+type memberCache struct {
+	mutex  sync.RWMutex
+	names  map[string]map[string]string // display names by guild and user
+	loaded map[string]bool              // guilds whose members were all requested
+}
+
+func newMemberCache() *memberCache {
+	return &memberCache{
+		names:  make(map[string]map[string]string),
+		loaded: make(map[string]bool),
+	}
+}
+
+func (mc *memberCache) Name(gid, uid string) (string, bool) {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+	name, ok := mc.names[gid][uid]
+	return name, ok
+}
+
+func (mc *memberCache) Loaded(gid string) bool {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+	return mc.loaded[gid]
+}
+
+func (mc *memberCache) Set(gid string, members ...*DG.Member) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	names, ok := mc.names[gid]
+	if !ok {
+		names = make(map[string]string)
+		mc.names[gid] = names
+	}
+	for _, m := range members {
+		if m == nil || m.User == nil {
+			continue
+		}
+		names[m.User.ID] = U.MemberName(m)
+	}
+}
+
+func (mc *memberCache) Remove(gid, uid string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	delete(mc.names[gid], uid)
+}
+
+// Forget drops the members of a guild the bot left.
+func (mc *memberCache) Forget(gid string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	delete(mc.names, gid)
+	delete(mc.loaded, gid)
+}
+
+// Load requests all the members of the guild, one page at a time.
+func (mc *memberCache) Load(s Session, gid string) error {
+	after := ""
+	for {
+		members, err := s.GuildMembers(gid, after, MembersPageSize)
+		if err != nil {
+			return err
+		}
+		mc.Set(gid, members...)
+		if len(members) < MembersPageSize {
+			break
+		}
+		after = members[len(members)-1].User.ID
+	}
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	mc.loaded[gid] = true
+	return nil
+}
+
+// memberName returns the display name of a guild member, requesting only this member if the
+// guild is not cached yet. It returns an empty string if the user is not a member.
+func (b *Bot) memberName(gid, uid string) string {
+	if name, ok := b.members.Name(gid, uid); ok || b.members.Loaded(gid) {
+		return name
+	}
+	member, err := b.s.GuildMember(gid, uid)
+	if err != nil {
+		return ""
+	}
+	b.members.Set(gid, member)
+	return U.MemberName(member)
+}
+
+// watchMembers keeps the member cache up to date with gateway events.
+func (b *Bot) watchMembers(ws *DG.Session) {
+	ws.AddHandler(func(_ *DG.Session, e *DG.GuildCreate) {
+		b.members.Set(e.ID, e.Members...)
+	})
+	ws.AddHandler(func(_ *DG.Session, e *DG.GuildDelete) {
+		b.members.Forget(e.ID)
+	})
+	ws.AddHandler(func(_ *DG.Session, e *DG.GuildMemberAdd) {
+		b.members.Set(e.GuildID, e.Member)
+	})
+	ws.AddHandler(func(_ *DG.Session, e *DG.GuildMemberUpdate) {
+		b.members.Set(e.GuildID, e.Member)
+	})
+	ws.AddHandler(func(_ *DG.Session, e *DG.GuildMemberRemove) {
+		if e.Member != nil && e.Member.User != nil {
+			b.members.Remove(e.GuildID, e.Member.User.ID)
+		}
+	})
+	ws.AddHandler(func(_ *DG.Session, e *DG.GuildMembersChunk) {
+		b.members.Set(e.GuildID, e.Members...)
+	})
+}
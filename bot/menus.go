@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	DG "github.com/bwmarrin/discordgo"
+)
+
+const MenuExpiryInterval = 5 * time.Second
+
+var ErrMenuExpired = errors.New("menu expired")
+
+type menuEntry struct {
+	mutex  sync.Mutex // serializes page changes and expiry of the menu
+	menu   Paginated
+	closed bool // the buttons of the menu were removed
+}
+
+// MenuManager keeps track of the menus whose pages can only be changed until they expire, and
+// disables their buttons once they do. Menus without an expiry are rebuilt from the database for
+// as long as their message exists, and are not tracked. It is safe for concurrent use.
+type MenuManager struct {
+	s     Session
+	log   Logger
+	mutex sync.Mutex // protects menus
+	menus map[string]*menuEntry
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once // closes stop
+}
+
+// NewMenuManager returns a menu manager whose expiry loop checks menus every interval.
+func NewMenuManager(s Session, log Logger, interval time.Duration) *MenuManager {
+	res := &MenuManager{
+		s:     s,
+		log:   log,
+		menus: make(map[string]*menuEntry),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go res.run(interval)
+	return res
+}
+
+func (mm *MenuManager) run(interval time.Duration) {
+	defer close(mm.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-mm.stop:
+			return
+		case now := <-ticker.C:
+			mm.Purge(now)
+		}
+	}
+}
+
+// Add registers a sent menu, if it expires.
+func (mm *MenuManager) Add(m Paginated) {
+	if m.Expires().IsZero() {
+		return
+	}
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	mm.menus[m.ID()] = &menuEntry{menu: m}
+}
+
+func (mm *MenuManager) Len() int {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	return len(mm.menus)
+}
+
+// Page runs the page change of the menu identified by ID. The changes of a tracked menu are
+// serialized with its expiry, so that they do not bring back the buttons of an expired menu:
+// ErrMenuExpired is returned once it expired.
+func (mm *MenuManager) Page(ID string, now time.Time, change func()) error {
+	mm.mutex.Lock()
+	entry, ok := mm.menus[ID]
+	mm.mutex.Unlock()
+	if !ok {
+		change()
+		return nil
+	}
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if entry.closed || entry.menu.Expired(now) {
+		return ErrMenuExpired
+	}
+	change()
+	return nil
+}
+
+// Purge disables the buttons of the menus expired at the given time, and forgets them.
+func (mm *MenuManager) Purge(now time.Time) {
+	expired := []*menuEntry{}
+	mm.mutex.Lock()
+	for ID, entry := range mm.menus {
+		if entry.menu.Expired(now) {
+			expired = append(expired, entry)
+			delete(mm.menus, ID)
+		}
+	}
+	remaining := len(mm.menus)
+	mm.mutex.Unlock()
+
+	if len(expired) == 0 {
+		return
+	}
+	for _, entry := range expired {
+		mm.disable(context.Background(), entry)
+	}
+	mm.log.Info("purged %d menus, remaining menus: %d", len(expired), remaining)
+}
+
+// Close stops the expiry loop and disables the buttons of all remaining menus, until ctx is done.
+// Their expiry would not be tracked anymore once the bot restarted.
+func (mm *MenuManager) Close(ctx context.Context) {
+	mm.once.Do(func() { close(mm.stop) })
+	<-mm.done
+
+	mm.mutex.Lock()
+	entries := mm.menus
+	mm.menus = make(map[string]*menuEntry)
+	mm.mutex.Unlock()
+
+	count := 0
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			mm.log.Warn("deadline reached, %d/%d menus closed", count, len(entries))
+			return
+		}
+		mm.disable(ctx, entry)
+		count++
+	}
+	mm.log.Info("closed %d menus", count)
+}
+
+func (mm *MenuManager) disable(ctx context.Context, entry *menuEntry) {
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	entry.closed = true
+	m := entry.menu
+	cID, mID := m.Message()
+	edit := DG.NewMessageEdit(cID, mID)
+	edit.Components = &[]DG.MessageComponent{}
+	if _, err := mm.s.ChannelMessageEditComplex(edit, DG.WithContext(ctx)); err != nil {
+		mm.log.WarnE(err, "disabling menu %s", m.ID())
+	}
+}
@@ -0,0 +1,213 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	DG "github.com/bwmarrin/discordgo"
+	LR "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func sentMenu(t *testing.T, s Session, nbPages int, ttl time.Duration) *Menu[string] {
+	list := []string{}
+	for i := 0; i < nbPages; i++ {
+		list = append(list, strconv.Itoa(i))
+	}
+	m := NewMenu(list, 1, "channel", "guild")
+	if ttl != 0 {
+		m.SetTTL(ttl)
+	}
+	if err := m.Send(s, nil); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMenuManager(t *testing.T) {
+	a := assert.New(t)
+	s := newFakeSession(0)
+	b := newTestBot(t, s)
+	mm := NewMenuManager(s, b.WithFields(LR.Fields{}), time.Hour)
+	defer mm.Close(context.Background())
+
+	t.Run("concurrent paging", func(t *testing.T) {
+		m := sentMenu(t, s, 10, time.Hour)
+		mm.Add(m)
+		var count atomic.Int64
+		wg := sync.WaitGroup{}
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				a.NoError(mm.Page(m.ID(), time.Now(), func() { count.Add(1) }))
+			}()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				mm.Purge(time.Now())
+			}()
+		}
+		wg.Wait()
+		a.Equal(int64(20), count.Load())
+	})
+
+	t.Run("untracked menu", func(t *testing.T) {
+		before := mm.Len()
+		mm.Add(sentMenu(t, s, 2, 0))
+		a.Equal(before, mm.Len())
+		paged := false
+		a.NoError(mm.Page("unknown", time.Now(), func() { paged = true }))
+		a.True(paged)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		before := mm.Len()
+		expired := []*Menu[string]{}
+		for i := 0; i < 3; i++ {
+			m := sentMenu(t, s, 2, -time.Second)
+			mm.Add(m)
+			expired = append(expired, m)
+		}
+		long := sentMenu(t, s, 2, time.Hour)
+		mm.Add(long)
+		a.ErrorIs(mm.Page(expired[0].ID(), time.Now(), func() { a.Fail("paged expired menu") }), ErrMenuExpired)
+
+		// A failed edit must not prevent the other menus from being purged
+		s.failing.Store(expired[0].mID, true)
+		mm.Purge(time.Now())
+		a.Equal(before+1, mm.Len())
+		for _, m := range expired[1:] {
+			msg := s.message(m.mID)
+			a.Empty(msg.Components, fmt.Sprintf("menu %s still has buttons", m.ID()))
+		}
+		a.NoError(mm.Page(long.ID(), time.Now(), func() {}))
+	})
+
+	t.Run("close", func(t *testing.T) {
+		long := sentMenu(t, s, 2, time.Hour)
+		mm.Add(long)
+		mm.Close(context.Background())
+		a.Zero(mm.Len())
+		a.Empty(s.message(long.mID).Components)
+	})
+}
+
+func buttonPress(gid, cid, mID, uid, customID string, values ...string) *DG.InteractionCreate {
+	return &DG.InteractionCreate{Interaction: &DG.Interaction{
+		Type:      DG.InteractionMessageComponent,
+		GuildID:   gid,
+		ChannelID: cid,
+		Message:   &DG.Message{ID: mID, ChannelID: cid},
+		Member:    &DG.Member{User: &DG.User{ID: uid}},
+		Data:      DG.MessageComponentInteractionData{CustomID: customID, Values: values},
+	}}
+}
+
+func TestMenuButton(t *testing.T) {
+	a := assert.New(t)
+	button := MenuButton{Kind: ScoreboardMenu, Action: NextPageAction, Page: 3, Expires: 1700000000, Params: []string{"1234"}}
+	res, ok := ParseMenuButton(button.CustomID())
+	a.True(ok)
+	a.Equal(button, res)
+	a.False(res.Expired(time.Unix(1700000000, 0)))
+	a.True(res.Expired(time.Unix(1700000001, 0)))
+	a.False(MenuButton{}.Expired(time.Now()))
+
+	_, ok = ParseMenuButton(NextPageLabel)
+	a.False(ok)
+}
+
+func TestPersistentMenu(t *testing.T) {
+	a := assert.New(t)
+	s := newFakeSession(0)
+	b := newTestBot(t, s)
+	serv := b.GetServer("guild")
+	for i := 0; i < 25; i++ {
+		serv.Users[fmt.Sprintf("user%d", i)] = []string{"m1i1"}
+	}
+	b.SaveServer(serv)
+
+	// Rebuilt from the database, without the menu being known to the bot
+	lb := b.leaderboardMenu(b.GetServer("guild"), "channel", WindowAll, RankingPoints)
+	components := lb.Components()
+	next := components[0].(DG.ActionsRow).Components[2].(DG.Button)
+	PageReact(b)(nil, buttonPress("guild", "channel", "message", "someone", next.CustomID))
+	reply := s.lastReply()
+	a.Equal(DG.InteractionResponseUpdateMessage, reply.Type)
+	a.Contains(reply.Data.Embeds[0].Footer.Text, "Page 2/3")
+	next = reply.Data.Components[0].(DG.ActionsRow).Components[2].(DG.Button)
+	button, _ := ParseMenuButton(next.CustomID)
+	a.Equal(3, button.Page)
+
+	// Jump to a page selected in the select menu
+	jump := components[1].(DG.ActionsRow).Components[0].(DG.SelectMenu)
+	a.Len(jump.Options, 3)
+	PageReact(b)(nil, buttonPress("guild", "channel", "message", "someone", jump.CustomID, "3"))
+	a.Contains(s.lastReply().Data.Embeds[0].Footer.Text, "Page 3/3")
+
+	// Jump to the page of the interacting user
+	me := components[0].(DG.ActionsRow).Components[4].(DG.Button)
+	a.Equal(MePageLabel, me.Label)
+	for _, uid := range []string{"user0", "user12", "user24"} {
+		page, ok := lb.Locate(uid)
+		a.True(ok)
+		PageReact(b)(nil, buttonPress("guild", "channel", "message", uid, me.CustomID))
+		a.Contains(s.lastReply().Data.Embeds[0].Footer.Text, fmt.Sprintf("Page %d/3", page))
+	}
+	PageReact(b)(nil, buttonPress("guild", "channel", "message", "someone", me.CustomID))
+	a.Equal(notInTheMenu, s.lastReply().Data.Content)
+
+	// Scoreboards can only be paged by their owner
+	sb := b.scoreboardMenu(b.GetServer("guild"), "user1", "channel")
+	components = sb.Components()
+	next = components[0].(DG.ActionsRow).Components[2].(DG.Button)
+	PageReact(b)(nil, buttonPress("guild", "channel", "message", "someone", next.CustomID))
+	a.Equal(DG.MessageFlagsEphemeral, s.lastReply().Data.Flags)
+	PageReact(b)(nil, buttonPress("guild", "channel", "message", "user1", next.CustomID))
+	a.Equal(DG.InteractionResponseUpdateMessage, s.lastReply().Type)
+}
+
+func TestMenuTTL(t *testing.T) {
+	a := assert.New(t)
+	s := newFakeSession(0)
+	b := newTestBot(t, s)
+	b.conf.MenuTTL = time.Hour
+	serv := b.GetServer("guild")
+	for i := 0; i < 25; i++ {
+		serv.Users[fmt.Sprintf("user%d", i)] = []string{"m1i1"}
+	}
+	b.SaveServer(serv)
+
+	lb := b.leaderboardMenu(b.GetServer("guild"), "channel", WindowAll, RankingPoints)
+	a.NoError(b.sendMenu(lb, nil))
+	a.Equal(1, b.Menus.Len())
+	next := lb.Components()[0].(DG.ActionsRow).Components[2].(DG.Button)
+	button, _ := ParseMenuButton(next.CustomID)
+	a.Equal(lb.Expires().Unix(), button.Expires)
+
+	// Rebuilt pages keep the expiry of the menu
+	PageReact(b)(nil, buttonPress("guild", "channel", lb.mID, "someone", next.CustomID))
+	reply := s.lastReply()
+	a.Contains(reply.Data.Embeds[0].Footer.Text, "Page 2/3")
+	next = reply.Data.Components[0].(DG.ActionsRow).Components[2].(DG.Button)
+	rebuilt, _ := ParseMenuButton(next.CustomID)
+	a.Equal(button.Expires, rebuilt.Expires)
+
+	// Expired buttons remove the components, also once the bot restarted and forgot the menu
+	button.Expires = time.Now().Add(-time.Minute).Unix()
+	PageReact(b)(nil, buttonPress("guild", "channel", "forgotten", "someone", button.CustomID()))
+	reply = s.lastReply()
+	a.Equal(DG.InteractionResponseUpdateMessage, reply.Type)
+	a.Empty(reply.Data.Components)
+	a.Empty(reply.Data.Embeds)
+
+	// The buttons of the menus that expire are disabled on shutdown
+	b.Menus.Close(context.Background())
+	a.Empty(s.message(lb.mID).Components)
+}
@@ -1,6 +1,7 @@
 package bot
 
 import (
+	"net/http"
 	"sync"
 	"time"
 
@@ -11,11 +12,12 @@ import (
 )
 
 const (
-	DefaultPrefix        = "b!"
-	DefaultMinDelay      = 120
-	DefaultVariableDelay = 781
-	DefaultStayTime      = 5 * time.Second
-	HistoryDepth         = 10
+	DefaultPrefix           = "b!"
+	DefaultMinDelay         = 120 * time.Second
+	DefaultVariableDelay    = 781
+	DefaultStayTime         = 5 * time.Second
+	DefaultShutdownDeadline = 10 * time.Second
+	HistoryDepth            = 10
 )
 
 var DefaultMemberPermissions int64 = DG.PermissionManageServer
@@ -29,39 +31,68 @@ type MonsterSpawn struct {
 type Game struct {
 	On            bool
 	Monsters      map[string]MonsterSpawn
-	NextSpawn     time.Time
 	MinDelay      time.Duration
 	StayTime      time.Duration
-	SpawnRate     int
 	VariableDelay int
 	Finished      bool
-	LastMessages  History
 	Winner        string
+	SeasonStart   time.Time                // last reset of the game
+	SpawnPolicy   string                   // name of the spawn policy, DefaultSpawnPolicy if empty
+	ChannelStates map[string]*ChannelState // spawn state of the channels, by channel ID
 }
 
-func (g *Game) Spawns(rng U.RNG) bool {
-	if !g.LastMessages.HasSeveralAuthors() || g.LastMessages.Span() > time.Hour {
-		// if g.LastMessages.Span() > time.Hour { // for debug
+// ChannelState is the spawn state of a channel: its cooldown, activity and monster pool.
+type ChannelState struct {
+	NextSpawn    time.Time
+	SpawnRate    int
+	LastMessages History
+	Activity     ActivityScore      // for the activity policy
+	Pool         map[string]float64 // weights of the monsters that can come, by ID, all monsters if empty
+}
+
+func newChannelState(now time.Time) *ChannelState {
+	return &ChannelState{LastMessages: newHistoryAt(now)}
+}
+
+// Channel returns the spawn state of a channel, creating it if needed.
+func (g *Game) Channel(cid string) *ChannelState {
+	if g.ChannelStates == nil {
+		g.ChannelStates = make(map[string]*ChannelState)
+	}
+	if _, ok := g.ChannelStates[cid]; !ok {
+		g.ChannelStates[cid] = newChannelState(time.Now())
+	}
+	return g.ChannelStates[cid]
+}
+
+func (c *ChannelState) Spawns(rng U.RNG) bool {
+	if !c.LastMessages.HasSeveralAuthors() || c.LastMessages.Span() > time.Hour {
+		// if c.LastMessages.Span() > time.Hour { // for debug
 		return false
 	}
-	return rng.PercentChance(g.SpawnRate)
+	return U.PercentChance(rng, c.SpawnRate)
 }
 
-func (g *Game) UpdateSpawnRate(rng U.RNG, msg *DG.Message) {
-	g.LastMessages = g.LastMessages.Update(msg)
-	span := g.LastMessages.Span()
+func (c *ChannelState) UpdateSpawnRate(rng U.RNG, msg *DG.Message) {
+	c.LastMessages = c.LastMessages.Update(msg)
+	c.raiseSpawnRate(rng)
+}
+
+// raiseSpawnRate raises the spawn rate according to the activity of the last messages.
+func (c *ChannelState) raiseSpawnRate(rng U.RNG) {
+	span := c.LastMessages.Span()
 	if span > time.Hour {
-		g.ResetSpawnRate()
-		g.SpawnRate += rng.Intn(2)
-	} else if g.LastMessages.Span() > 15*time.Minute {
+		c.ResetSpawnRate()
+		c.SpawnRate += rng.Intn(2)
+	} else if c.LastMessages.Span() > 15*time.Minute {
 		return
 	} else {
-		g.SpawnRate += rng.Intn(3)
+		c.SpawnRate += rng.Intn(3)
 	}
 }
 
-func (g *Game) ResetSpawnRate() {
-	g.SpawnRate = 0
+func (c *ChannelState) ResetSpawnRate() {
+	c.SpawnRate = 0
 }
 
 type Server struct {
@@ -72,10 +103,23 @@ type Server struct {
 	Admins   []string
 	Users    map[string][]string
 	Lb       Leaderboard
+	Global   bool                   `storm:"index"` // takes part in the global leaderboard
+	Pack     string                 // hash of the items the collections were gathered with
+	Pity     map[string]PityCounter // unlucky grabs of the players, when the pity system is on
+	Banned   map[string]Ban         // players excluded from the game by the admins
+	OptedOut []string               // players who left the game
+	// ForgottenBans are the bans of the players who erased their data, by hash of their ID
+	ForgottenBans map[string]Ban
+	// AdminChannel is where the players suspected of cheating are flagged, if set
+	AdminChannel string
 }
 
 // CanSpawn returns true only if an item can spawn in the given channel
 func (s Server) CanSpawn(cid string) bool {
+	return s.canSpawnAt(cid, time.Now())
+}
+
+func (s Server) canSpawnAt(cid string, now time.Time) bool {
 	_, channelHasSpawn := s.G.Monsters[cid]
 	// If the game is not on, the channel is not a spawn channel, or the channel already has
 	// a spawn, a spawn cannot happen
@@ -83,15 +127,27 @@ func (s Server) CanSpawn(cid string) bool {
 		return false
 	}
 	// cooldown check
-	delayOk := time.Now().Local().After(s.G.NextSpawn)
-	return delayOk
+	state, ok := s.G.ChannelStates[cid]
+	return !ok || now.Local().After(state.NextSpawn)
 }
 
-// Cooldown sets the server game on cooldown
+// Cooldown sets all the spawn channels of the server game on cooldown
 func (s *Server) Cooldown(rng U.RNG) {
+	for _, cid := range s.Channels {
+		s.cooldownAt(rng, cid, time.Now())
+	}
+}
+
+// ChannelCooldown sets a channel of the server game on cooldown
+func (s *Server) ChannelCooldown(rng U.RNG, cid string) {
+	s.cooldownAt(rng, cid, time.Now())
+}
+
+func (s *Server) cooldownAt(rng U.RNG, cid string, now time.Time) {
+	state := s.G.Channel(cid)
 	randomDelay := time.Duration(rng.Intn(s.G.VariableDelay)) * time.Second
-	s.G.NextSpawn = time.Now().Local().Add(s.G.MinDelay) // Base cooldown of 2mn
-	s.G.NextSpawn = s.G.NextSpawn.Add(randomDelay)       // variable cooldown, up to 15mn total
+	state.NextSpawn = now.Local().Add(s.G.MinDelay)    // Base cooldown of 2mn
+	state.NextSpawn = state.NextSpawn.Add(randomDelay) // variable cooldown, up to 15mn total
 }
 
 func (s Server) IsAdmin(uid string) bool {
@@ -102,8 +158,14 @@ func (s Server) IsAdmin(uid string) bool {
 	return U.Contains(s.Admins, uid)
 }
 
+// IsOwner returns true if the user runs the bot.
+func (b *Bot) IsOwner(uid string) bool {
+	return U.Contains(b.conf.Owners, uid)
+}
+
 type Bot struct {
-	s                   *DG.Session
+	s                   Session     // Discord REST API
+	ws                  *DG.Session // Discord gateway connection
 	db                  *storm.DB
 	Log                 *LR.Logger
 	UserID              string
@@ -111,12 +173,31 @@ type Bot struct {
 	Items               map[string]Item
 	Monsters            map[string]Monster
 	MonsterIds          []string
-	Menus               map[string]Menu
+	pack                string // hash of the configured items
+	Menus               *MenuManager
 	EqualMonsterChances bool
 	InteractionHandlers InteractionHandlers
 	Commands            []Command
-	mutex               sync.Mutex
-	rng                 U.RNG
+	guildLocks          map[string]*sync.Mutex
+	guildLocksMutex     sync.Mutex // protects guildLocks
+	ranks               map[string]*rankIndex
+	ranksMutex          sync.Mutex // protects ranks
+	members             *memberCache
+	rng                 U.RNG // game rolls
+	seed                int64 // seed of rng
+	conf                Config
+	confLoaded          time.Time
+	health              *http.Server
+	healthMutex         sync.Mutex // protects notReadySince
+	notReadySince       time.Time  // when the gateway session was first seen not ready
+	thumbnails          *thumbnailCache
+	backupMutex         sync.Mutex // serializes backups, protects lastBackup
+	lastBackup          time.Time
+	backupsStop         chan struct{} // stops the scheduled backups
+	backupsDone         chan struct{}
+	closing             bool           // set once Stop is called, no new handler may start
+	closingMutex        sync.Mutex     // protects closing and the handlers wait group
+	handlers            sync.WaitGroup // in-progress handlers
 }
 
 type BotAction func(*Bot, CommandParameters)
@@ -127,6 +208,7 @@ type Command struct {
 	appCmd         *DG.ApplicationCommand
 	Options        Options
 	Admin          bool
+	Owner          bool // only the owners of the bot can use it
 	AlwaysTrigger  bool
 	ModifiesServer bool
 }
@@ -163,12 +245,21 @@ var commandList = []Command{
 
 	// Score commands
 	{
-		Name:           "leaderboard",
-		Action:         ShowLeaderboard,
-		appCmd:         &DG.ApplicationCommand{Description: "Show the server leaderboard"},
-		Options:        Options{},
+		Name:   "leaderboard",
+		Action: ShowLeaderboard,
+		appCmd: &DG.ApplicationCommand{Description: "Show the server leaderboard"},
+		Options: Options{
+			{Name: "window", Description: "period of the leaderboard", Type: TypeString, Optional: true, Choices: windows},
+			{Name: "ranking", Description: "how players are ranked", Type: TypeString, Optional: true, Choices: rankingNames},
+		},
 		ModifiesServer: true,
 	},
+	{
+		Name:    "globallb",
+		Action:  ShowGlobalLeaderboard,
+		appCmd:  &DG.ApplicationCommand{Description: "Show the leaderboard of all participating servers"},
+		Options: Options{},
+	},
 	{
 		Name:           "score",
 		Action:         ShowScore,
@@ -176,6 +267,32 @@ var commandList = []Command{
 		Options:        Options{},
 		ModifiesServer: true,
 	},
+	{
+		Name:           "optout",
+		Action:         OptOut,
+		appCmd:         &DG.ApplicationCommand{Description: "Leave the game, deleting your items and score"},
+		Options:        Options{},
+		ModifiesServer: true,
+	},
+	{
+		Name:           "optin",
+		Action:         OptIn,
+		appCmd:         &DG.ApplicationCommand{Description: "Play again after leaving the game"},
+		Options:        Options{},
+		ModifiesServer: true,
+	},
+	{
+		Name:    "mydata",
+		Action:  MyData,
+		appCmd:  &DG.ApplicationCommand{Description: "Receive everything the bot stores about you"},
+		Options: Options{},
+	},
+	{
+		Name:    "forgetme",
+		Action:  ForgetMe,
+		appCmd:  &DG.ApplicationCommand{Description: "Erase your data from every server"},
+		Options: Options{{Name: "confirm", Description: "\"confirm\" to erase your data", Type: TypeString, Optional: true, Choices: []string{"confirm"}}},
+	},
 
 	// Help command
 	{
@@ -197,7 +314,7 @@ var commandList = []Command{
 		Name:           "setprefix",
 		Action:         SetPrefix,
 		appCmd:         &DG.ApplicationCommand{Description: "Change the bot prefix"},
-		Options:        Options{{"prefix", "new prefix to use", TypeString}},
+		Options:        Options{{Name: "prefix", Description: "new prefix to use", Type: TypeString}},
 		Admin:          true,
 		ModifiesServer: true,
 	},
@@ -206,8 +323,8 @@ var commandList = []Command{
 		Action: SetCooldown,
 		appCmd: &DG.ApplicationCommand{Description: "Change the spawn cooldown"},
 		Options: Options{
-			{"minimum", "minimum cooldown duration (in seconds)", TypeInteger},
-			{"maximum", "maximum cooldown duration (in seconds)", TypeInteger},
+			{Name: "minimum", Description: "minimum cooldown duration (in seconds)", Type: TypeInteger},
+			{Name: "maximum", Description: "maximum cooldown duration (in seconds)", Type: TypeInteger},
 		},
 		Admin:          true,
 		ModifiesServer: true,
@@ -216,7 +333,7 @@ var commandList = []Command{
 		Name:           "setstay",
 		Action:         SetStay,
 		appCmd:         &DG.ApplicationCommand{Description: "Change how long a monsters stays idle"},
-		Options:        Options{{"duration", "duration in seconds", TypeInteger}},
+		Options:        Options{{Name: "duration", Description: "duration in seconds", Type: TypeInteger}},
 		Admin:          true,
 		ModifiesServer: true,
 	},
@@ -224,7 +341,7 @@ var commandList = []Command{
 		Name:           "addchan",
 		Action:         AddChannel,
 		appCmd:         &DG.ApplicationCommand{Description: "Add a channel where monsters can spawn"},
-		Options:        Options{{"channel", "channel to add", TypeChannel}},
+		Options:        Options{{Name: "channel", Description: "channel to add", Type: TypeChannel}},
 		Admin:          true,
 		ModifiesServer: true,
 	},
@@ -232,7 +349,7 @@ var commandList = []Command{
 		Name:           "rmvchan",
 		Action:         RemoveChannel,
 		appCmd:         &DG.ApplicationCommand{Description: "Remove a channel where monsters can spawn"},
-		Options:        Options{{"channel", "channel to remove", TypeChannel}},
+		Options:        Options{{Name: "channel", Description: "channel to remove", Type: TypeChannel}},
 		Admin:          true,
 		ModifiesServer: true,
 	},
@@ -247,7 +364,7 @@ var commandList = []Command{
 		Name:           "addadmin",
 		Action:         AddAdmin,
 		appCmd:         &DG.ApplicationCommand{Description: "Add a bot administrator"},
-		Options:        Options{{"user", "user that shall be an administrator", TypeUser}},
+		Options:        Options{{Name: "user", Description: "user that shall be an administrator", Type: TypeUser}},
 		Admin:          true,
 		ModifiesServer: true,
 	},
@@ -255,7 +372,7 @@ var commandList = []Command{
 		Name:           "rmvadmin",
 		Action:         RemoveAdmin,
 		appCmd:         &DG.ApplicationCommand{Description: "Remove a bot administrator"},
-		Options:        Options{{"user", "user that be removed from the administrators list", TypeUser}},
+		Options:        Options{{Name: "user", Description: "user that be removed from the administrators list", Type: TypeUser}},
 		Admin:          true,
 		ModifiesServer: true,
 	},
@@ -277,10 +394,114 @@ var commandList = []Command{
 		Name:           "play",
 		Action:         Play,
 		appCmd:         &DG.ApplicationCommand{Description: "Start or stop the game"},
-		Options:        Options{{"state", "game status: \"on\" or \"off\"", TypeString}},
+		Options:        Options{{Name: "state", Description: "game status: \"on\" or \"off\"", Type: TypeString}},
+		Admin:          true,
+		ModifiesServer: true,
+	},
+	{
+		Name:           "setpolicy",
+		Action:         SetPolicy,
+		appCmd:         &DG.ApplicationCommand{Description: "Choose how chat messages make visitors come"},
+		Options:        Options{{Name: "policy", Description: "spawn policy", Type: TypeString, Choices: policyNames}},
+		Admin:          true,
+		ModifiesServer: true,
+	},
+	{
+		Name:   "setpool",
+		Action: SetPool,
+		appCmd: &DG.ApplicationCommand{Description: "Choose the monsters coming to a spawn channel"},
+		Options: Options{
+			{Name: "channel", Description: "spawn channel", Type: TypeChannel},
+			{Name: "monsters", Description: "monster IDs or names with optional weights (eg 1:2,witch), all if empty", Type: TypeString, Optional: true},
+		},
+		Admin:          true,
+		ModifiesServer: true,
+	},
+	{
+		Name:           "setadminchan",
+		Action:         SetAdminChannel,
+		appCmd:         &DG.ApplicationCommand{Description: "Choose where players suspected of cheating are flagged"},
+		Options:        Options{{Name: "channel", Description: "admin channel, none to stop flagging there", Type: TypeChannel, Optional: true}},
+		Admin:          true,
+		ModifiesServer: true,
+	},
+	{
+		Name:    "flags",
+		Action:  ShowFlags,
+		appCmd:  &DG.ApplicationCommand{Description: "Review the players suspected of cheating"},
+		Options: Options{{Name: "user", Description: "player whose flags to review, all pending flags if empty", Type: TypeUser, Optional: true}},
+		Admin:   true,
+	},
+	{
+		Name:    "dismiss",
+		Action:  DismissFlags,
+		appCmd:  &DG.ApplicationCommand{Description: "Mark the flags of a player as reviewed"},
+		Options: Options{{Name: "user", Description: "flagged player", Type: TypeUser}},
+		Admin:   true,
+	},
+	{
+		Name:   "ban",
+		Action: BanUser,
+		appCmd: &DG.ApplicationCommand{Description: "Exclude a player from the game"},
+		Options: Options{
+			{Name: "user", Description: "player to ban", Type: TypeUser},
+			{Name: "leaderboards", Description: "\"hide\" to hide the player from the leaderboards", Type: TypeString, Optional: true, Choices: banVisibilities},
+			{Name: "reason", Description: "reason of the ban", Type: TypeString, Optional: true},
+		},
+		Admin:          true,
+		ModifiesServer: true,
+	},
+	{
+		Name:           "unban",
+		Action:         UnbanUser,
+		appCmd:         &DG.ApplicationCommand{Description: "Let a banned player play again"},
+		Options:        Options{{Name: "user", Description: "player to unban", Type: TypeUser}},
+		Admin:          true,
+		ModifiesServer: true,
+	},
+	{
+		Name:   "revoke",
+		Action: RevokeItems,
+		appCmd: &DG.ApplicationCommand{Description: "Take items back from a player"},
+		Options: Options{
+			{Name: "user", Description: "player", Type: TypeUser},
+			{Name: "item", Description: "ID of the item, all items if empty", Type: TypeString, Optional: true},
+		},
+		Admin:          true,
+		ModifiesServer: true,
+	},
+	{
+		Name:           "setglobal",
+		Action:         SetGlobal,
+		appCmd:         &DG.ApplicationCommand{Description: "Take part in the global leaderboard or not"},
+		Options:        Options{{Name: "state", Description: "participation: \"on\" or \"off\"", Type: TypeString}},
 		Admin:          true,
 		ModifiesServer: true,
 	},
+	{
+		Name:    "export",
+		Action:  ExportData,
+		appCmd:  &DG.ApplicationCommand{Description: "Export the leaderboard, the players' items and the settings"},
+		Options: Options{{Name: "format", Description: "file format", Type: TypeString, Optional: true, Choices: exportFormats}},
+		Admin:   true,
+	},
+	{
+		Name:   "import",
+		Action: ImportData,
+		appCmd: &DG.ApplicationCommand{Description: "Import the players' items of an exported file"},
+		Options: Options{
+			{Name: "file", Description: "JSON or CSV file made by the export command", Type: TypeAttachment},
+			{Name: "mode", Description: "merge with or replace the current items", Type: TypeString, Optional: true, Choices: importModes},
+		},
+		Admin: true,
+	},
+	{
+		Name:    "backup",
+		Action:  BackupData,
+		appCmd:  &DG.ApplicationCommand{Description: "Save a snapshot of the bot database"},
+		Options: Options{},
+		Owner:   true,
+	},
 	{
 		Name:           "reset",
 		Action:         Reset,
@@ -1,42 +1,88 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
+	"image"
+	"strconv"
 	"strings"
 	"time"
 
+	R "github.com/ashyaa/birtho/render"
 	DG "github.com/bwmarrin/discordgo"
 	embed "github.com/clinet/discordgo-embed"
 )
 
+// Page actions of menu components
+const (
+	FirstPageAction    = "first"
+	PreviousPageAction = "prev"
+	NextPageAction     = "next"
+	LastPageAction     = "last"
+	MePageAction       = "me"   // page of the interacting user
+	JumpPageAction     = "jump" // page selected in the select menu
+
+	menuButtonPrefix = "menu"
+	maxSelectOptions = 25 // Discord limit
+)
+
 const (
 	FirstPageLabel    = "\u2060 \u2060 \u2060 First \u2060 \u2060 \u2060"
 	PreviousPageLabel = "Previous"
 	NextPageLabel     = "\u2060 \u2060 \u2060 Next \u2060 \u2060 \u2060"
 	LastPageLabel     = "\u2060 \u2060 \u2060 Last \u2060 \u2060 \u2060"
+	MePageLabel       = "Me"
+	JumpPlaceholder   = "Jump to..."
 )
 
-type Listable interface {
-	string
+// Paginated is a menu whose page can be changed, whatever the type of its elements.
+type Paginated interface {
+	ID() string
+	Message() (cID, mID string)
+	CanPage(uid string) bool
+	Expires() time.Time
+	SetExpiry(expires time.Time)
+	Expired(now time.Time) bool
+	SetPage(page int)
+	Locate(uid string) (int, bool)
+	Embed() *DG.MessageEmbed
+	Render() (*DG.MessageEmbed, []*DG.File)
+	Components() []DG.MessageComponent
+	Send(s Session, i *DG.Interaction) error
 }
 
-type Menu struct {
-	cID      string   // Discord channel ID
-	mID      string   // Discord message ID
-	gID      string   // Discord guild ID
-	L        []string // List to be displayed in a embed message
-	Images   []string // List of images to be set as thumbnails on each page
-	header   string
-	footer   string
-	expires  time.Time
-	page     int // Current page
-	maxPage  int
-	size     int // Number of elements per page
-	title    string
-	subtitle string
+// Menu is a list of elements of type T displayed in an embed message, one page at a time.
+// Elements are rendered as lines of a code block, or as embed fields.
+type Menu[T any] struct {
+	cID       string   // Discord channel ID
+	mID       string   // Discord message ID
+	gID       string   // Discord guild ID
+	L         []T      // List to be displayed in a embed message
+	Images    []string // List of images to be set as thumbnails on each page
+	line      func(T) string
+	field     func(T) *DG.MessageEmbedField
+	pageLabel func(page int, elements []T) string
+	locate    func(element T, uid string) bool
+	draw      func(page int, elements []T) (image.Image, error)
+	imageName string
+	imageOnly bool // the image replaces the text of the page
+	header    string
+	footer    string
+	expires   time.Time // pages cannot be changed after it, unless zero
+	owner     string    // ID of the user who requested the menu
+	ownerOnly bool      // only the owner can change pages
+	kind      string    // kind of the menu, to rebuild it from the database
+	params    []string  // parameters needed to rebuild a persistent menu
+	page      int       // Current page
+	maxPage   int
+	size      int // Number of elements per page
+	title     string
+	subtitle  string
 }
 
-func NewMenu(list []string, size int, cID, gID string) Menu {
+// NewMenu returns a menu of size elements per page, rendered with fmt.Sprint in a code block
+// unless SetLines or SetFields is used.
+func NewMenu[T any](list []T, size int, cID, gID string) *Menu[T] {
 	maxPage := len(list) / size
 	if len(list)%size > 0 {
 		maxPage += 1
@@ -44,211 +90,470 @@ func NewMenu(list []string, size int, cID, gID string) Menu {
 	if maxPage == 0 {
 		maxPage = 1
 	}
-	res := Menu{
+	res := Menu[T]{
 		L:       list,
+		line:    func(elt T) string { return fmt.Sprint(elt) },
 		page:    1,
-		expires: time.Now().Local().Add(time.Duration(60) * time.Second),
 		maxPage: maxPage,
 		size:    size,
 		cID:     cID,
 		gID:     gID,
-		Images:  make([]string, maxPage),
 	}
-	return res
+	return &res
+}
+
+// SetLines renders each element as a line of a code block, below the header.
+func (m *Menu[T]) SetLines(header string, line func(T) string) {
+	m.header = header
+	m.line = line
+	m.field = nil
+}
+
+// SetFields renders each element as an embed field.
+func (m *Menu[T]) SetFields(field func(T) *DG.MessageEmbedField) {
+	m.field = field
+}
+
+// SetPageLabel sets the label of each page in the jump select menu.
+func (m *Menu[T]) SetPageLabel(label func(page int, elements []T) string) {
+	m.pageLabel = label
+}
+
+// SetLocator adds a "Me" button jumping to the page of the first element matching the interacting
+// user.
+func (m *Menu[T]) SetLocator(locate func(element T, uid string) bool) {
+	m.locate = locate
+}
+
+// SetImage renders each page as an image attached to the message. If imageOnly is true, the image
+// replaces the text of the page. The text is used alone when the image cannot be drawn.
+func (m *Menu[T]) SetImage(name string, draw func(page int, elements []T) (image.Image, error), imageOnly bool) {
+	m.imageName = name
+	m.draw = draw
+	m.imageOnly = imageOnly
+}
+
+// SetTTL sets how long the menu pages can be changed from now on.
+func (m *Menu[T]) SetTTL(ttl time.Duration) {
+	m.SetExpiry(time.Now().Add(ttl))
+}
+
+// SetExpiry sets when the menu pages stop changing. Menus with a zero expiry never expire.
+func (m *Menu[T]) SetExpiry(expires time.Time) {
+	m.expires = expires
+}
+
+func (m *Menu[T]) Expires() time.Time {
+	return m.expires
+}
+
+func (m *Menu[T]) Expired(now time.Time) bool {
+	return !m.expires.IsZero() && now.After(m.expires)
+}
+
+// SetOwner sets the user who requested the menu. If ownerOnly is true, other users cannot change
+// its pages.
+func (m *Menu[T]) SetOwner(uid string, ownerOnly bool) {
+	m.owner = uid
+	m.ownerOnly = ownerOnly
+}
+
+func (m *Menu[T]) CanPage(uid string) bool {
+	return !m.ownerOnly || m.owner == uid
+}
+
+// SetKind makes the menu persistent: its components encode the kind, page, expiry and parameters
+// needed to rebuild it from the database, so it keeps working after the bot restarted. Menus
+// without a kind cannot change pages.
+func (m *Menu[T]) SetKind(kind string, params ...string) {
+	m.kind = kind
+	m.params = params
+}
+
+// SetPage sets the current page, within the page range.
+func (m *Menu[T]) SetPage(page int) {
+	m.page = min(max(page, 1), m.maxPage)
+}
+
+// Locate returns the page of the first element matching the user uid.
+func (m *Menu[T]) Locate(uid string) (int, bool) {
+	if m.locate == nil {
+		return 0, false
+	}
+	for i, elt := range m.L {
+		if m.locate(elt, uid) {
+			return i/m.size + 1, true
+		}
+	}
+	return 0, false
 }
 
-func (m *Menu) SetTitle(title string) {
+func (m *Menu[T]) SetTitle(title string) {
 	m.title = title
 }
 
-func (m *Menu) SetSubtitle(subtitle string) {
+func (m *Menu[T]) SetSubtitle(subtitle string) {
 	m.subtitle = subtitle
 }
 
-func (m *Menu) SetImages(images []string) {
-	nbImages := max(m.maxPage, len(images))
-	for i := 0; i < nbImages; i++ {
-		m.Images[i] = images[i]
-	}
+func (m *Menu[T]) SetImages(images []string) {
+	m.Images = images
 }
 
-func (m *Menu) SetHeader(header string) {
+func (m *Menu[T]) SetHeader(header string) {
 	m.header = header
 }
 
-func (m *Menu) SetFooter(footer string) {
+func (m *Menu[T]) SetFooter(footer string) {
 	m.footer = footer
 }
 
-func (m *Menu) GetComponents() *[]DG.MessageComponent {
-	return &[]DG.MessageComponent{
-		DG.ActionsRow{
-			Components: []DG.MessageComponent{
-				DG.Button{
-					Label:    FirstPageLabel,
-					CustomID: FirstPageLabel,
-					Disabled: m.page == 1,
-				},
-				DG.Button{
-					Label:    PreviousPageLabel,
-					CustomID: PreviousPageLabel,
-					Disabled: m.page == 1,
-				},
-				DG.Button{
-					Label:    NextPageLabel,
-					CustomID: NextPageLabel,
-					Disabled: m.page == m.maxPage,
-				},
-				DG.Button{
-					Label:    LastPageLabel,
-					CustomID: LastPageLabel,
-					Disabled: m.page == m.maxPage,
-				},
-			},
+// MenuButton is a component of a menu, leading to a given page.
+type MenuButton struct {
+	Kind    string
+	Action  string
+	Page    int
+	Expires int64 // Unix time the menu expires at, 0 if it never does
+	Params  []string
+}
+
+func (mb MenuButton) CustomID() string {
+	fields := []string{menuButtonPrefix, mb.Kind, mb.Action, strconv.Itoa(mb.Page), strconv.FormatInt(mb.Expires, 10)}
+	return strings.Join(append(fields, mb.Params...), ":")
+}
+
+// Expired returns true if the menu of the button expired at the given time.
+func (mb MenuButton) Expired(now time.Time) bool {
+	return mb.Expires != 0 && now.Unix() > mb.Expires
+}
+
+// ParseMenuButton returns the menu button encoded in a component custom ID, and false if the ID is
+// not one of a menu component.
+func ParseMenuButton(customID string) (MenuButton, bool) {
+	fields := strings.Split(customID, ":")
+	if len(fields) < 5 || fields[0] != menuButtonPrefix {
+		return MenuButton{}, false
+	}
+	page, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return MenuButton{}, false
+	}
+	expires, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return MenuButton{}, false
+	}
+	return MenuButton{Kind: fields[1], Action: fields[2], Page: page, Expires: expires, Params: fields[5:]}, true
+}
+
+// customID returns the custom ID of the component of the given action, leading to page.
+func (m *Menu[T]) customID(action string, page int) string {
+	button := MenuButton{Kind: m.kind, Action: action, Page: page, Params: m.params}
+	if !m.expires.IsZero() {
+		button.Expires = m.expires.Unix()
+	}
+	return button.CustomID()
+}
+
+func (m *Menu[T]) Components() []DG.MessageComponent {
+	buttons := []DG.MessageComponent{
+		DG.Button{
+			Label:    FirstPageLabel,
+			CustomID: m.customID(FirstPageAction, 1),
+			Disabled: m.page == 1,
+		},
+		DG.Button{
+			Label:    PreviousPageLabel,
+			CustomID: m.customID(PreviousPageAction, m.page-1),
+			Disabled: m.page == 1,
+		},
+		DG.Button{
+			Label:    NextPageLabel,
+			CustomID: m.customID(NextPageAction, m.page+1),
+			Disabled: m.page == m.maxPage,
 		},
+		DG.Button{
+			Label:    LastPageLabel,
+			CustomID: m.customID(LastPageAction, m.maxPage),
+			Disabled: m.page == m.maxPage,
+		},
+	}
+	if m.locate != nil {
+		buttons = append(buttons, DG.Button{
+			Label:    MePageLabel,
+			Style:    DG.SuccessButton,
+			CustomID: m.customID(MePageAction, 0),
+		})
+	}
+	res := []DG.MessageComponent{DG.ActionsRow{Components: buttons}}
+	if m.maxPage > 1 {
+		res = append(res, DG.ActionsRow{Components: []DG.MessageComponent{m.jumpSelect()}})
+	}
+	return res
+}
+
+// jumpSelect returns the select menu listing the pages around the current one.
+func (m *Menu[T]) jumpSelect() DG.SelectMenu {
+	first := max(1, min(m.page-maxSelectOptions/2, m.maxPage-maxSelectOptions+1))
+	last := min(m.maxPage, first+maxSelectOptions-1)
+	options := []DG.SelectMenuOption{}
+	for page := first; page <= last; page++ {
+		label := fmt.Sprintf("Page %d", page)
+		if m.pageLabel != nil {
+			label = m.pageLabel(page, m.elements(page))
+		}
+		options = append(options, DG.SelectMenuOption{
+			Label:   label,
+			Value:   strconv.Itoa(page),
+			Default: page == m.page,
+		})
+	}
+	return DG.SelectMenu{
+		CustomID:    m.customID(JumpPageAction, 0),
+		Placeholder: JumpPlaceholder,
+		Options:     options,
 	}
 }
 
-func (m *Menu) Send(s *DG.Session, i *DG.Interaction) error {
-	msg, err := SendEmbed(s, i, m.cID, m.render(), *m.GetComponents())
+func (m *Menu[T]) Send(s Session, i *DG.Interaction) error {
+	e, files := m.Render()
+	msg, err := SendEmbed(s, i, m.cID, e, m.Components(), files...)
 	if err == nil {
 		m.mID = msg.ID
 	}
 	return err
 }
 
+// sendMenu sends the menu, whose pages can be changed for the configured menu TTL.
+func (b *Bot) sendMenu(m Paginated, i *DG.Interaction) error {
+	if b.conf.MenuTTL > 0 {
+		m.SetExpiry(time.Now().Add(b.conf.MenuTTL))
+	}
+	if err := m.Send(b.s, i); err != nil {
+		return err
+	}
+	b.Menus.Add(m)
+	return nil
+}
+
 func id(mID, cID, gID string) string {
 	return mID + "." + cID + "." + gID
 }
 
-func (m *Menu) ID() string {
+func (m *Menu[T]) ID() string {
 	return id(m.mID, m.cID, m.gID)
 }
 
-func (m *Menu) render() *DG.MessageEmbed {
-	minIndex := (m.page - 1) * m.size
-	if minIndex < 0 {
-		minIndex = 0
+func (m *Menu[T]) Message() (string, string) {
+	return m.cID, m.mID
+}
+
+// elements returns the elements of the given page.
+func (m *Menu[T]) elements(page int) []T {
+	minIndex := max((page-1)*m.size, 0)
+	maxIndex := min(page*m.size, len(m.L))
+	if minIndex > maxIndex {
+		return []T{}
 	}
-	maxIndex := (m.page) * m.size
-	if maxIndex > len(m.L) {
-		maxIndex = len(m.L)
+	return m.L[minIndex:maxIndex]
+}
+
+// Embed returns the embed displaying the current page as text.
+func (m *Menu[T]) Embed() *DG.MessageEmbed {
+	return m.embed(true)
+}
+
+// Render returns the embed displaying the current page, and the image of the page to attach to
+// the message if any.
+func (m *Menu[T]) Render() (*DG.MessageEmbed, []*DG.File) {
+	if m.draw == nil {
+		return m.Embed(), nil
 	}
+	img, err := m.draw(m.page, m.elements(m.page))
+	if err != nil {
+		return m.Embed(), nil
+	}
+	buf, err := R.PNG(img)
+	if err != nil {
+		return m.Embed(), nil
+	}
+	res := m.embed(!m.imageOnly)
+	res.Image = &DG.MessageEmbedImage{URL: "attachment://" + m.imageName}
+	return res, []*DG.File{{Name: m.imageName, ContentType: "image/png", Reader: buf}}
+}
+
+func (m *Menu[T]) embed(withText bool) *DG.MessageEmbed {
+	elements := m.elements(m.page)
 	text := ""
 	if m.subtitle != "" {
 		text = m.subtitle + "\n"
 	}
-	if len(m.L) != 0 {
-		data := append([]string{m.header}, m.L[minIndex:maxIndex]...)
+	fields := []*DG.MessageEmbedField{}
+	if !withText {
+		elements = []T{}
+	}
+	if m.field != nil {
+		for _, elt := range elements {
+			fields = append(fields, m.field(elt))
+		}
+	} else if len(elements) != 0 {
+		data := []string{m.header}
+		for _, elt := range elements {
+			data = append(data, m.line(elt))
+		}
 		text += "```\n" + strings.Join(data, "\n") + "\n```"
 	}
 	footer := fmt.Sprintf("Page %d/%d", m.page, m.maxPage)
 	if m.footer != "" {
 		footer = m.footer + "\n" + footer
 	}
-	return embed.NewEmbed().
+	thumbnail := ""
+	if m.page <= len(m.Images) {
+		thumbnail = m.Images[m.page-1]
+	}
+	res := embed.NewEmbed().
 		SetTitle(m.title).
 		SetDescription(text).
-		SetThumbnail(m.Images[m.page-1]).
+		SetThumbnail(thumbnail).
 		SetFooter(footer).
 		SetColor(0x555555).MessageEmbed
-}
-
-func (m *Menu) PreviousPage(s *DG.Session) {
-	m.page -= 1
-	if m.page < 1 {
-		m.page = 1
-	}
-	edit := DG.NewMessageEdit(m.cID, m.mID).SetEmbed(m.render())
-	edit.Components = m.GetComponents()
-	s.ChannelMessageEditComplex(edit)
-}
-
-func (m *Menu) NextPage(s *DG.Session) {
-	m.page += 1
-	if m.page > m.maxPage {
-		m.page = m.maxPage
-	}
-	edit := DG.NewMessageEdit(m.cID, m.mID).SetEmbed(m.render())
-	edit.Components = m.GetComponents()
-	s.ChannelMessageEditComplex(edit)
-}
-
-func (m *Menu) FirstPage(s *DG.Session) {
-	m.page = 1
-	edit := DG.NewMessageEdit(m.cID, m.mID).SetEmbed(m.render())
-	edit.Components = m.GetComponents()
-	s.ChannelMessageEditComplex(edit)
-}
-
-func (m *Menu) LastPage(s *DG.Session) {
-	m.page = m.maxPage
-	edit := DG.NewMessageEdit(m.cID, m.mID).SetEmbed(m.render())
-	edit.Components = m.GetComponents()
-	s.ChannelMessageEditComplex(edit)
-}
-
-func purgeMenus(b *Bot) func() {
-	return func() {
-		toRemove := []string{}
-		now := time.Now().Local()
-		for ID, menu := range b.Menus {
-			if now.After(menu.expires) {
-				toRemove = append(toRemove, ID)
-			}
-		}
-		for _, ID := range toRemove {
-			if m, ok := b.Menus[ID]; ok {
-				msg, err := b.s.ChannelMessage(b.Menus[ID].cID, b.Menus[ID].mID)
-				if err != nil || len(msg.Embeds) == 0 {
-					return
-				}
-				edit := DG.NewMessageEdit(m.cID, m.mID).SetEmbed(m.render())
-				edit.Components = &[]DG.MessageComponent{}
-				b.s.ChannelMessageEditComplex(edit)
-				delete(b.Menus, ID)
-			}
-		}
-		b.Info("purged menus, remaining menus: %d", len(b.Menus))
-	}
+	res.Fields = fields
+	return res
 }
 
 func PageReact(b *Bot) func(*DG.Session, *DG.InteractionCreate) {
-	return func(s *DG.Session, i *DG.InteractionCreate) {
+	return func(_ *DG.Session, i *DG.InteractionCreate) {
+		s := b.s
 		channel := i.ChannelID
+		uid := ""
+		if i.Member != nil {
+			uid = i.Member.User.ID
+		}
+		log := b.CommandLogger(i.GuildID, channel, uid, "page")
 		if i.Message == nil {
-			b.Error("page interaction does not come from a button")
+			log.Error("page interaction does not come from a button")
 			s.InteractionRespond(i.Interaction, &DG.InteractionResponse{
 				Type: DG.InteractionResponseUpdateMessage,
 			})
 			return
 		}
-		mID := i.Message.ID
-
-		menu, ok := b.Menus[id(mID, channel, i.GuildID)]
+		data := i.MessageComponentData()
+		button, ok := ParseMenuButton(data.CustomID)
 		if !ok {
-			b.Info("nok")
+			log.Warn("unknown component %s", data.CustomID)
+			s.InteractionRespond(i.Interaction, &DG.InteractionResponse{
+				Type: DG.InteractionResponseUpdateMessage,
+			})
 			return
 		}
-		if menu.mID != mID || menu.cID != channel || menu.gID != i.GuildID {
-			b.Info("incorrect")
+		now := time.Now()
+		if button.Expired(now) {
+			closeMenu(s, i.Interaction)
 			return
 		}
-		switch name := i.MessageComponentData().CustomID; name {
-		case FirstPageLabel:
-			menu.FirstPage(s)
-		case PreviousPageLabel:
-			menu.PreviousPage(s)
-		case NextPageLabel:
-			menu.NextPage(s)
-		case LastPageLabel:
-			menu.LastPage(s)
-		default:
-			b.Warn("unknown button %s", name)
-		}
-		b.Menus[menu.ID()] = menu
-		s.InteractionRespond(i.Interaction, &DG.InteractionResponse{
-			Type: DG.InteractionResponseUpdateMessage,
+		err := b.Menus.Page(id(i.Message.ID, channel, i.GuildID), now, func() {
+			b.pagePersistentMenu(i.Interaction, uid, button, data.Values, log)
 		})
+		if errors.Is(err, ErrMenuExpired) {
+			closeMenu(s, i.Interaction)
+		}
+	}
+}
+
+// closeMenu removes the components of the expired menu of the interaction.
+func closeMenu(s Session, i *DG.Interaction) {
+	s.InteractionRespond(i, &DG.InteractionResponse{
+		Type: DG.InteractionResponseUpdateMessage,
+		Data: &DG.InteractionResponseData{Components: []DG.MessageComponent{}},
+	})
+}
+
+const (
+	notMenuOwner = "Only the person who requested this menu can change its page."
+	notInTheMenu = "You are not in this list yet."
+)
+
+// respondPage changes the page of the menu according to the component used, and updates the menu
+// message.
+func (b *Bot) respondPage(i *DG.Interaction, menu Paginated, uid string, button MenuButton, values []string, log Logger) {
+	switch button.Action {
+	case FirstPageAction, PreviousPageAction, NextPageAction, LastPageAction:
+		menu.SetPage(button.Page)
+	case JumpPageAction:
+		if len(values) == 0 {
+			break
+		}
+		page, err := strconv.Atoi(values[0])
+		if err != nil {
+			log.Warn("invalid page %s", values[0])
+			break
+		}
+		menu.SetPage(page)
+	case MePageAction:
+		page, ok := menu.Locate(uid)
+		if !ok {
+			SendEphemeral(b.s, i, notInTheMenu)
+			return
+		}
+		menu.SetPage(page)
+	default:
+		log.Warn("unknown menu action %s", button.Action)
+	}
+	e, files := menu.Render()
+	err := b.s.InteractionRespond(i, &DG.InteractionResponse{
+		Type: DG.InteractionResponseUpdateMessage,
+		Data: &DG.InteractionResponseData{
+			Embeds:      []*DG.MessageEmbed{e},
+			Components:  menu.Components(),
+			Files:       files,
+			Attachments: &[]*DG.MessageAttachment{}, // replace the image of the previous page
+		},
+	})
+	if err != nil {
+		log.ErrorE(err, "updating menu %s", menu.ID())
+	}
+}
+
+// menuBuilders rebuild persistent menus from the database, by menu kind.
+var menuBuilders = map[string]func(b *Bot, serv Server, cID string, params []string) (Paginated, bool){
+	LeaderboardMenu: func(b *Bot, serv Server, cID string, params []string) (Paginated, bool) {
+		if len(params) == 2 {
+			return b.leaderboardMenu(serv, cID, params[0], params[1]), true
+		}
+		return b.leaderboardMenu(serv, cID, WindowAll, RankingPoints), true
+	},
+	GlobalLeaderboardMenu: func(b *Bot, serv Server, cID string, _ []string) (Paginated, bool) {
+		return b.globalLeaderboardMenu(serv.ID, cID), true
+	},
+	ScoreboardMenu: func(b *Bot, serv Server, cID string, params []string) (Paginated, bool) {
+		if len(params) != 1 {
+			return nil, false
+		}
+		return b.scoreboardMenu(serv, params[0], cID), true
+	},
+}
+
+// pagePersistentMenu rebuilds the page of a persistent menu requested by a component.
+func (b *Bot) pagePersistentMenu(i *DG.Interaction, uid string, button MenuButton, values []string, log Logger) {
+	build, ok := menuBuilders[button.Kind]
+	if !ok {
+		log.Warn("unknown menu kind %s", button.Kind)
+		b.s.InteractionRespond(i, &DG.InteractionResponse{Type: DG.InteractionResponseUpdateMessage})
+		return
+	}
+	unlock := b.lockGuild(i.GuildID)
+	menu, ok := build(b, b.GetServer(i.GuildID), i.ChannelID, button.Params)
+	unlock()
+	if !ok {
+		log.Warn("invalid parameters for menu kind %s: %v", button.Kind, button.Params)
+		b.s.InteractionRespond(i, &DG.InteractionResponse{Type: DG.InteractionResponseUpdateMessage})
+		return
+	}
+	if !menu.CanPage(uid) {
+		SendEphemeral(b.s, i, notMenuOwner)
+		return
+	}
+	if button.Expires != 0 {
+		menu.SetExpiry(time.Unix(button.Expires, 0))
 	}
+	b.respondPage(i, menu, uid, button, values, log)
 }
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	R "github.com/ashyaa/birtho/render"
+	U "github.com/ashyaa/birtho/util"
+)
+
+// PityOptions are the thresholds of unlucky grabs after which a better item is guaranteed. A zero
+// threshold disables its guarantee.
+type PityOptions struct {
+	Uncommon   int `json:"uncommon,omitempty" yaml:"uncommon,omitempty"`     // grabs without an uncommon or rare item
+	Rare       int `json:"rare,omitempty" yaml:"rare,omitempty"`             // grabs without a rare item
+	Duplicates int `json:"duplicates,omitempty" yaml:"duplicates,omitempty"` // grabs in a row giving a duplicate
+}
+
+// Enabled returns true if any guarantee is set.
+func (o PityOptions) Enabled() bool {
+	return o.Uncommon > 0 || o.Rare > 0 || o.Duplicates > 0
+}
+
+// PityCounter counts the unlucky grabs of a player since their last uncommon item, rare item and
+// new item.
+type PityCounter struct {
+	Uncommon   int
+	Rare       int
+	Duplicates int
+}
+
+// after returns the counter once the player got item.
+func (c PityCounter) after(item Item, duplicate bool) PityCounter {
+	c.Uncommon++
+	c.Rare++
+	c.Duplicates++
+	if item.Rarity() >= R.Uncommon {
+		c.Uncommon = 0
+	}
+	if item.Rarity() >= R.Rare {
+		c.Rare = 0
+	}
+	if !duplicate {
+		c.Duplicates = 0
+	}
+	return c
+}
+
+// guaranteed returns the item the monster gives to a player whose counter reached a threshold,
+// favoring the items the player is missing. It returns false if no threshold is reached, or if
+// the monster has no item to satisfy it.
+func (o PityOptions) guaranteed(rng U.RNG, m Monster, owned []string, c PityCounter) (Item, bool) {
+	guarantees := []struct {
+		threshold, count int
+		accepts          func(Item) bool
+	}{
+		{o.Rare, c.Rare, func(i Item) bool { return i.Rarity() >= R.Rare }},
+		{o.Uncommon, c.Uncommon, func(i Item) bool { return i.Rarity() >= R.Uncommon }},
+		{o.Duplicates, c.Duplicates, func(i Item) bool { return !U.Contains(owned, i.ID) }},
+	}
+	for _, g := range guarantees {
+		if g.threshold <= 0 || g.count < g.threshold {
+			continue
+		}
+		candidates, missing := []Item{}, []Item{}
+		for _, item := range m.Items {
+			if !g.accepts(item) {
+				continue
+			}
+			candidates = append(candidates, item)
+			if !U.Contains(owned, item.ID) {
+				missing = append(missing, item)
+			}
+		}
+		if len(missing) > 0 {
+			candidates = missing
+		}
+		if len(candidates) > 0 {
+			return candidates[rng.Intn(len(candidates))], true
+		}
+	}
+	return Item{}, false
+}
+
+// rollItem returns the item a monster gives to a player, with the bad luck protection if on, and
+// counts their unlucky grabs in the server when the pity system is on.
+func (b *Bot) rollItem(rng U.RNG, m Monster, serv *Server, uid string) Item {
+	var item Item
+	if b.conf.BadLuck.Enabled {
+		item = m.RandomItemFor(rng, serv.Users[uid], b.conf.BadLuck.duplicateWeight())
+	} else {
+		item = m.RandomItem(rng, b.Log)
+	}
+	if !b.conf.Pity.Enabled() {
+		return item
+	}
+	counter := serv.Pity[uid]
+	if forced, ok := b.conf.Pity.guaranteed(rng, m, serv.Users[uid], counter); ok {
+		item = forced
+	}
+	if serv.Pity == nil {
+		serv.Pity = make(map[string]PityCounter)
+	}
+	serv.Pity[uid] = counter.after(item, U.Contains(serv.Users[uid], item.ID))
+	return item
+}
+
+// describe returns the number of grabs before each guarantee of the counter, at most.
+func (o PityOptions) describe(c PityCounter) string {
+	parts := []string{}
+	for _, g := range []struct {
+		name             string
+		threshold, count int
+	}{
+		{"rare", o.Rare, c.Rare},
+		{"uncommon", o.Uncommon, c.Uncommon},
+		{"new item", o.Duplicates, c.Duplicates},
+	} {
+		if g.threshold <= 0 {
+			continue
+		}
+		left := g.threshold - g.count + 1
+		if left < 1 {
+			left = 1
+		}
+		parts = append(parts, fmt.Sprintf("%s within `%d`", g.name, left))
+	}
+	return "Guaranteed: " + strings.Join(parts, ", ") + " grabs"
+}
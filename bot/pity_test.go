@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPity(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	ghost := b.Monsters["1"]
+	candy, lantern, skull := ghost.Items[0], ghost.Items[1], ghost.Items[2]
+	unlucky := fixedRNG(0) // always rolls the common item
+
+	t.Run("disabled", func(t *testing.T) {
+		serv := b.NewServer("disabled")
+		for i := 0; i < 20; i++ {
+			a.Equal(candy.ID, b.rollItem(unlucky, ghost, &serv, "user").ID)
+		}
+		a.Empty(serv.Pity)
+	})
+
+	t.Run("rare", func(t *testing.T) {
+		b.conf.Pity = PityOptions{Rare: 3}
+		serv := b.NewServer("rare")
+		got := []string{}
+		for i := 0; i < 8; i++ {
+			got = append(got, b.rollItem(unlucky, ghost, &serv, "user").ID)
+		}
+		a.Equal([]string{candy.ID, candy.ID, candy.ID, skull.ID, candy.ID, candy.ID, candy.ID, skull.ID}, got)
+	})
+
+	t.Run("uncommon", func(t *testing.T) {
+		b.conf.Pity = PityOptions{Uncommon: 2}
+		serv := b.NewServer("uncommon")
+		serv.Users["user"] = []string{skull.ID}
+		got := []string{}
+		for i := 0; i < 3; i++ {
+			got = append(got, b.rollItem(unlucky, ghost, &serv, "user").ID)
+		}
+		// The missing item is favored over the owned rare item
+		a.Equal([]string{candy.ID, candy.ID, lantern.ID}, got)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		b.conf.Pity = PityOptions{Duplicates: 2}
+		serv := b.NewServer("duplicates")
+		serv.Users["user"] = []string{candy.ID}
+		a.Equal(candy.ID, b.rollItem(unlucky, ghost, &serv, "user").ID)
+		a.Equal(candy.ID, b.rollItem(unlucky, ghost, &serv, "user").ID)
+		a.NotEqual(candy.ID, b.rollItem(unlucky, ghost, &serv, "user").ID)
+		a.Zero(serv.Pity["user"].Duplicates)
+	})
+
+	t.Run("score", func(t *testing.T) {
+		b.conf.Pity = PityOptions{Uncommon: 5, Rare: 10}
+		serv := b.NewServer("score")
+		serv.Pity = map[string]PityCounter{"user": {Uncommon: 2, Rare: 12}}
+		menu := b.scoreboardMenu(serv, "user", "channel")
+		a.Contains(menu.subtitle, "Guaranteed: rare within `1`, uncommon within `4` grabs")
+	})
+	b.conf.Pity = PityOptions{}
+}
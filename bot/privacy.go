@@ -0,0 +1,266 @@
+package bot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	U "github.com/ashyaa/birtho/util"
+	DG "github.com/bwmarrin/discordgo"
+)
+
+// pseudonymPrefix starts the IDs replacing forgotten users in the records kept for the statistics
+// of the servers.
+const pseudonymPrefix = "forgotten-"
+
+// forgottenUser records when a user erased their data, so that imports of older exports do not bring
+// it back. Only a hash of the user ID is kept.
+type forgottenUser struct {
+	Hash string `storm:"id"`
+	Time time.Time
+}
+
+func userHash(uid string) string {
+	sum := sha256.Sum256([]byte(uid))
+	return hex.EncodeToString(sum[:])
+}
+
+// forgottenSince returns true if the user erased their data after the time.
+func (b *Bot) forgottenSince(uid string, t time.Time) bool {
+	var res forgottenUser
+	if err := b.db.One("Hash", userHash(uid), &res); err != nil {
+		return false
+	}
+	return res.Time.After(t)
+}
+
+// IsPseudonym returns true if the user ID replaces a forgotten user.
+func IsPseudonym(uid string) bool {
+	return strings.HasPrefix(uid, pseudonymPrefix)
+}
+
+// UserData is everything stored about a user, as exported by the mydata command.
+type UserData struct {
+	UID     string           `json:"user"`
+	Time    time.Time        `json:"time"`
+	Servers []UserServerData `json:"servers"`
+	Grabs   []GrabEvent      `json:"grabs"`
+	Flags   []CheatFlag      `json:"flags"`
+}
+
+// UserServerData is what a server stores about a user.
+type UserServerData struct {
+	Guild    string       `json:"guild"`
+	Name     string       `json:"name,omitempty"` // leaderboard name
+	Score    int          `json:"score"`
+	Items    []string     `json:"items"`
+	Admin    bool         `json:"admin"`
+	Winner   bool         `json:"winner"`
+	OptedOut bool         `json:"opted-out"`
+	Pity     *PityCounter `json:"pity,omitempty"`
+	Ban      *Ban         `json:"ban,omitempty"`
+	Banned   []string     `json:"banned,omitempty"`   // players banned by the user
+	Messages []time.Time  `json:"messages,omitempty"` // last messages kept in the channel histories
+}
+
+// serverData returns what the server stores about the user, and false if it stores nothing.
+func serverData(serv Server, uid string) (UserServerData, bool) {
+	res := UserServerData{Guild: serv.ID, Items: serv.Users[uid]}
+	_, found := serv.Users[uid]
+	for _, sb := range serv.Lb {
+		if sb.UID == uid {
+			res.Name, res.Score, found = sb.Name, sb.Score, true
+		}
+	}
+	res.Admin = U.Contains(serv.Admins, uid)
+	res.Winner = serv.G.Winner == uid
+	res.OptedOut = U.Contains(serv.OptedOut, uid)
+	if c, ok := serv.Pity[uid]; ok {
+		res.Pity = &c
+	}
+	if ban, ok := serv.ban(uid); ok {
+		res.Ban = &ban
+	}
+	for banned, ban := range serv.Banned {
+		if ban.By == uid {
+			res.Banned = append(res.Banned, banned)
+		}
+	}
+	for _, state := range serv.G.ChannelStates {
+		for _, msg := range state.LastMessages {
+			if msg.Author == uid {
+				res.Messages = append(res.Messages, msg.Time)
+			}
+		}
+	}
+	found = found || res.Admin || res.Winner || res.OptedOut || res.Pity != nil || res.Ban != nil ||
+		len(res.Banned) > 0 || len(res.Messages) > 0
+	return res, found
+}
+
+// UserData gathers what all the servers store about the user.
+func (b *Bot) UserData(uid string) (UserData, error) {
+	res := UserData{UID: uid, Time: time.Now(), Servers: []UserServerData{}, Grabs: []GrabEvent{}, Flags: []CheatFlag{}}
+	servers, err := b.Servers()
+	if err != nil {
+		return res, err
+	}
+	for _, serv := range servers {
+		if data, ok := serverData(serv, uid); ok {
+			res.Servers = append(res.Servers, data)
+		}
+	}
+	err = b.db.Find("UID", uid, &res.Grabs)
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		return res, err
+	}
+	err = b.db.Find("UID", uid, &res.Flags)
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		return res, err
+	}
+	return res, nil
+}
+
+// newPseudonym returns a random ID replacing a forgotten user. It does not use the game RNG, so
+// that seeded games replay the same.
+func newPseudonym() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return pseudonymPrefix + hex.EncodeToString(buf), nil
+}
+
+// forget erases the user from the server. The user is replaced with the pseudonym in the histories
+// of the channels, which still count their messages, and in the bans they gave. Their own ban is
+// kept under the hash of their ID, so that it still applies if they come back.
+func (b *Bot) forget(serv Server, uid, pseudonym string) Server {
+	if ban, ok := serv.Banned[uid]; ok {
+		if serv.ForgottenBans == nil {
+			serv.ForgottenBans = make(map[string]Ban)
+		}
+		serv.ForgottenBans[userHash(uid)] = ban
+		delete(serv.Banned, uid)
+	}
+	serv = b.RemovePlayer(serv, uid)
+	serv.Admins = U.Remove(serv.Admins, uid)
+	serv.OptedOut = U.Remove(serv.OptedOut, uid)
+	for banned, ban := range serv.Banned {
+		if ban.By == uid {
+			ban.By = pseudonym
+			serv.Banned[banned] = ban
+		}
+	}
+	for _, state := range serv.G.ChannelStates {
+		for i, msg := range state.LastMessages {
+			if msg.Author == uid {
+				state.LastMessages[i].Author = pseudonym
+			}
+		}
+	}
+	b.members.Remove(serv.ID, uid)
+	return serv
+}
+
+// Forget erases the user from all the servers. The grabs and flags of the user are kept for the
+// statistics of the servers, under a pseudonym. Returns the number of servers the user was erased
+// from.
+func (b *Bot) Forget(uid string) (int, error) {
+	servers, err := b.Servers()
+	if err != nil {
+		return 0, err
+	}
+	pseudonym, err := newPseudonym()
+	if err != nil {
+		return 0, err
+	}
+	if err := b.db.Save(&forgottenUser{Hash: userHash(uid), Time: time.Now()}); err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, serv := range servers {
+		if _, ok := serverData(serv, uid); !ok {
+			continue
+		}
+		unlock := b.lockGuild(serv.ID)
+		b.SaveServer(b.forget(b.GetServer(serv.ID), uid, pseudonym))
+		unlock()
+		count++
+	}
+
+	var grabs []GrabEvent
+	err = b.db.Find("UID", uid, &grabs)
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		return count, err
+	}
+	for _, event := range grabs {
+		if err := b.db.UpdateField(&event, "UID", pseudonym); err != nil {
+			return count, err
+		}
+	}
+	var flags []CheatFlag
+	err = b.db.Find("UID", uid, &flags)
+	if err != nil && !errors.Is(err, storm.ErrNotFound) {
+		return count, err
+	}
+	for _, f := range flags {
+		if err := b.db.UpdateField(&f, "UID", pseudonym); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func MyData(b *Bot, p CommandParameters) {
+	data, err := b.UserData(p.UID)
+	if err != nil {
+		p.Log.ErrorE(err, "gathering user data")
+		SendText(b.s, p.I, p.CID, "Export failed.")
+		return
+	}
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		p.Log.ErrorE(err, "encoding user data")
+		SendText(b.s, p.I, p.CID, "Export failed.")
+		return
+	}
+	dm, err := b.s.UserChannelCreate(p.UID)
+	if err == nil {
+		_, err = SendFile(b.s, nil, dm.ID, "Everything the bot stores about you:", &DG.File{
+			Name:        fmt.Sprintf("birtho-%s-%s.json", p.UID, data.Time.Format("20060102-150405")),
+			ContentType: "application/json",
+			Reader:      bytes.NewReader(encoded),
+		})
+	}
+	if err != nil {
+		p.Log.ErrorE(err, "sending user data")
+		SendText(b.s, p.I, p.CID, "Could not send you a direct message, check your privacy settings.")
+		return
+	}
+	SendText(b.s, p.I, p.CID, "Your data was sent to you in a direct message!")
+}
+
+func ForgetMe(b *Bot, p CommandParameters) {
+	if _, ok := p.Options["confirm"]; !ok {
+		msg := fmt.Sprintf("This erases your items, scores and settings in every server, and cannot be undone. "+
+			"Your past grabs are only kept anonymously. Use `%sforgetme confirm` to proceed.", p.S.Prefix)
+		SendText(b.s, p.I, p.CID, msg)
+		return
+	}
+	count, err := b.Forget(p.UID)
+	if err != nil {
+		p.Log.ErrorE(err, "forgetting user")
+		SendText(b.s, p.I, p.CID, "Deletion failed, please try again later.")
+		return
+	}
+	p.Log.Info("user erased from %d servers", count)
+	msg := fmt.Sprintf("Erased your data from %d servers. The database backups made before keep it until they are rotated out.", count)
+	SendText(b.s, p.I, p.CID, msg)
+}
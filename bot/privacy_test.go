@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivacy(t *testing.T) {
+	const (
+		channel = "300000000000000001"
+		alice   = "400000000000000001"
+		bob     = "400000000000000002"
+	)
+	a := assert.New(t)
+	s := newFakeSession(0)
+	b := newTestBot(t, s)
+	for _, gid := range []string{"guild", "other", "unrelated"} {
+		serv := b.NewServer(gid)
+		serv.G.On = true
+		serv.Channels = []string{channel}
+		b.SaveServer(serv)
+	}
+	serv := b.GetServer("other")
+	serv.Admins = []string{alice}
+	serv.Banned = map[string]Ban{bob: {By: alice, Time: time.Now()}}
+	b.SaveServer(serv)
+	grabAfter(b, "guild", channel, alice, time.Second)
+	grabAfter(b, "guild", channel, bob, time.Second)
+	HandlerFromMessageCreate(b, b.command("spawn"))(nil, messageCreate("other", channel, alice, "hello"))
+	b.db.Save(&CheatFlag{Guild: "guild", UID: alice, Rule: RuleFast, Time: time.Now()})
+
+	t.Run("mydata", func(t *testing.T) {
+		HandlerFromMessageCreate(b, b.command("mydata"))(nil, messageCreate("guild", "channel", alice, "b!mydata"))
+		var data UserData
+		s.files.Range(func(_, content any) bool {
+			a.NoError(json.Unmarshal(content.([]byte), &data))
+			return false
+		})
+		a.Equal(alice, data.UID)
+		a.Len(data.Servers, 2)
+		a.Len(data.Grabs, 1)
+		a.Len(data.Flags, 1)
+		for _, serv := range data.Servers {
+			switch serv.Guild {
+			case "guild":
+				a.Len(serv.Items, 1)
+				a.Positive(serv.Score)
+			case "other":
+				a.True(serv.Admin)
+				a.Equal([]string{bob}, serv.Banned)
+				a.Len(serv.Messages, 1)
+			}
+		}
+	})
+
+	t.Run("forgetme", func(t *testing.T) {
+		HandlerFromMessageCreate(b, b.command("forgetme"))(nil, messageCreate("guild", "channel", alice, "b!forgetme"))
+		data, err := b.UserData(alice)
+		a.NoError(err)
+		a.Len(data.Servers, 2)
+
+		HandlerFromMessageCreate(b, b.command("forgetme"))(nil, messageCreate("guild", "channel", alice, "b!forgetme confirm"))
+		data, err = b.UserData(alice)
+		a.NoError(err)
+		a.Empty(data.Servers)
+		a.Empty(data.Grabs)
+		a.Empty(data.Flags)
+
+		// The grabs are kept under a pseudonym, hidden from the leaderboards
+		grabs := b.Grabs("guild", time.Time{})
+		a.Len(grabs, 2)
+		pseudonym := ""
+		for _, event := range grabs {
+			if event.UID != bob {
+				pseudonym = event.UID
+			}
+		}
+		a.True(IsPseudonym(pseudonym))
+		lb := b.leaderboardMenu(b.GetServer("guild"), "channel", WindowToday, RankingGrabs).L
+		a.Len(lb, 1)
+		a.Equal(bob, lb[0].UID)
+		other := b.GetServer("other")
+		a.Equal(pseudonym, other.Banned[bob].By)
+		a.Equal(pseudonym, other.G.ChannelStates[channel].LastMessages[HistoryDepth-1].Author)
+		a.Contains(b.GetServer("guild").Users, bob)
+
+		// A banned user is erased everywhere, and stays banned under the hash of their ID
+		HandlerFromMessageCreate(b, b.command("forgetme"))(nil, messageCreate("guild", "channel", bob, "b!forgetme confirm"))
+		a.NotContains(b.GetServer("guild").Users, bob)
+		other = b.GetServer("other")
+		a.NotContains(other.Banned, bob)
+		a.True(other.Excluded(bob))
+		a.False(b.GetServer("guild").Excluded(bob))
+		data, err = b.UserData(bob)
+		a.NoError(err)
+		a.Len(data.Servers, 1)
+		a.NotNil(data.Servers[0].Ban)
+
+		admin := b.GetServer("other")
+		admin.Admins = append(admin.Admins, "admin")
+		b.SaveServer(admin)
+		HandlerFromMessageCreate(b, b.command("unban"))(nil, messageCreate("other", "channel", "admin", "b!unban <@"+bob+">"))
+		a.False(b.GetServer("other").Excluded(bob))
+		a.Empty(b.GetServer("other").ForgottenBans)
+	})
+}
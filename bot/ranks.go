@@ -0,0 +1,137 @@
+package bot
+
+// rankIndex indexes the stored leaderboard of a server: the position of each player in the
+// leaderboard, and the number of players by score in a Fenwick tree, so scores are updated and
+// ranks computed in O(log n) without sorting the leaderboard. It must be used under the lock of
+// its guild.
+type rankIndex struct {
+	positions map[string]int // position of the players in the stored leaderboard
+	counts    []int          // Fenwick tree of the number of players by score, 1-based
+	sorted    Leaderboard    // cached ranked leaderboard, reset when a score or name changes
+}
+
+func newRankIndex(lb Leaderboard) *rankIndex {
+	max := 0
+	for _, sb := range lb {
+		if sb.Score > max {
+			max = sb.Score
+		}
+	}
+	res := &rankIndex{positions: make(map[string]int, len(lb))}
+	res.build(lb, max)
+	return res
+}
+
+// build fills the tree with the scores of lb, for scores up to max.
+func (ri *rankIndex) build(lb Leaderboard, max int) {
+	ri.counts = make([]int, max+2)
+	for i, sb := range lb {
+		ri.positions[sb.UID] = i
+		ri.add(sb.Score, 1)
+	}
+	ri.sorted = nil
+}
+
+func (ri *rankIndex) add(score, delta int) {
+	if score < 0 {
+		score = 0
+	}
+	for i := score + 1; i < len(ri.counts); i += i & -i {
+		ri.counts[i] += delta
+	}
+}
+
+// atMost returns the number of players whose score is lower or equal to score.
+func (ri *rankIndex) atMost(score int) int {
+	if score < 0 {
+		return 0
+	}
+	if score > len(ri.counts)-2 {
+		score = len(ri.counts) - 2
+	}
+	res := 0
+	for i := score + 1; i > 0; i -= i & -i {
+		res += ri.counts[i]
+	}
+	return res
+}
+
+// Rank returns the rank of a player with the given score: players with the same score share the
+// same rank.
+func (ri *rankIndex) Rank(score int) int {
+	return 1 + len(ri.positions) - ri.atMost(score)
+}
+
+// Position returns the position of the player in the stored leaderboard.
+func (ri *rankIndex) Position(lb Leaderboard, uid string) (int, bool) {
+	pos, ok := ri.positions[uid]
+	if !ok || pos >= len(lb) || lb[pos].UID != uid {
+		return 0, false
+	}
+	return pos, true
+}
+
+// Add appends a new player to the stored leaderboard.
+func (ri *rankIndex) Add(lb Leaderboard, sb ScoreBoard) Leaderboard {
+	lb = append(lb, sb)
+	ri.positions[sb.UID] = len(lb) - 1
+	ri.grow(lb, sb.Score)
+	ri.add(sb.Score, 1)
+	ri.sorted = nil
+	return lb
+}
+
+// SetScore changes the score of the player at the given position of the stored leaderboard.
+func (ri *rankIndex) SetScore(lb Leaderboard, pos, score int) {
+	ri.add(lb[pos].Score, -1)
+	lb[pos].Score = score
+	ri.grow(lb, score)
+	ri.add(score, 1)
+	ri.sorted = nil
+}
+
+// grow rebuilds the tree if it cannot hold the score, which only happens if the configured items
+// changed.
+func (ri *rankIndex) grow(lb Leaderboard, score int) {
+	if score+2 <= len(ri.counts) {
+		return
+	}
+	max := 2 * len(ri.counts)
+	if score > max {
+		max = score
+	}
+	// lb already holds the new score, so it must not be counted again
+	ri.build(lb, max)
+	ri.add(score, -1)
+}
+
+// Ranked returns the leaderboard sorted by score with the ranks of the players, sorting it only
+// if it changed since the last call.
+func (ri *rankIndex) Ranked(lb Leaderboard) Leaderboard {
+	if ri.sorted == nil {
+		ri.sorted = make(Leaderboard, len(lb))
+		copy(ri.sorted, lb)
+		ri.sorted.sort()
+	}
+	return ri.sorted
+}
+
+// rankIndex returns the rank index of the server, building it if the stored leaderboard changed
+// without it.
+func (b *Bot) rankIndex(serv Server) *rankIndex {
+	b.ranksMutex.Lock()
+	defer b.ranksMutex.Unlock()
+	ri, ok := b.ranks[serv.ID]
+	if !ok || len(ri.positions) != len(serv.Lb) {
+		ri = newRankIndex(serv.Lb)
+		b.ranks[serv.ID] = ri
+	}
+	return ri
+}
+
+// dropRankIndex forgets the rank index of a guild whose leaderboard was replaced.
+func (b *Bot) dropRankIndex(gid string) {
+	b.ranksMutex.Lock()
+	defer b.ranksMutex.Unlock()
+	delete(b.ranks, gid)
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	DG "github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// randomLeaderboard returns a leaderboard of n players with random scores up to max.
+func randomLeaderboard(r *rand.Rand, n, max int) Leaderboard {
+	lb := Leaderboard{}
+	for i := 0; i < n; i++ {
+		lb = append(lb, ScoreBoard{UID: fmt.Sprintf("user%d", i), Score: r.Intn(max + 1)})
+	}
+	return lb
+}
+
+func TestRankIndex(t *testing.T) {
+	a := assert.New(t)
+	r := rand.New(rand.NewSource(1))
+	lb := randomLeaderboard(r, 200, 50)
+	ri := newRankIndex(lb)
+
+	check := func() {
+		sorted := make(Leaderboard, len(lb))
+		copy(sorted, lb)
+		sorted.sort()
+		for _, sb := range sorted {
+			pos, ok := ri.Position(lb, sb.UID)
+			a.True(ok)
+			a.Equal(sb.Rank, rankString(ri.Rank(lb[pos].Score)), sb.UID)
+		}
+		a.Equal(sorted, ri.Ranked(lb))
+	}
+	check()
+
+	for i := 0; i < 500; i++ {
+		pos := r.Intn(len(lb))
+		ri.SetScore(lb, pos, r.Intn(51))
+	}
+	check()
+
+	t.Run("new players", func(t *testing.T) {
+		lb = ri.Add(lb, ScoreBoard{UID: "top", Score: 1000}) // larger than the tree
+		lb = ri.Add(lb, ScoreBoard{UID: "bottom", Score: 0})
+		a.Equal(1, ri.Rank(1000))
+		check()
+	})
+}
+
+func TestLeaderboardNames(t *testing.T) {
+	a := assert.New(t)
+	s := newFakeSession(0)
+	uids := s.addMembers(2500)
+	b := newTestBot(t, s)
+	serv := b.NewServer("guild")
+	for _, uid := range []string{uids[0], uids[1200], uids[2499]} {
+		serv.Users[uid] = []string{"m1i1"}
+	}
+	serv.Users["gone"] = []string{"m1i2"}
+
+	lb := b.getLeaderBoard(serv)
+	a.Len(lb, 4)
+	a.Equal("gone", lb[0].UID)
+	a.Equal("1st", lb[0].Rank)
+	a.Equal("2nd", lb[1].Rank)
+	names := map[string]string{}
+	for _, sb := range lb {
+		names[sb.UID] = sb.Name
+	}
+	a.Equal("member1200", names[uids[1200]])
+	a.Equal("member2499", names[uids[2499]])
+	a.Equal(int64(3), s.calls.Load()) // 3 pages of members
+
+	// Names follow gateway member updates without requesting the members again
+	b.members.Set("guild", &DG.Member{Nick: "nick", User: &DG.User{ID: uids[0]}})
+	serv = b.GetServer("guild")
+	lb = b.getLeaderBoard(serv)
+	for _, sb := range lb {
+		names[sb.UID] = sb.Name
+	}
+	a.Equal("nick", names[uids[0]])
+	a.Equal(int64(3), s.calls.Load())
+}
+
+const benchmarkPlayers = 50000
+
+func BenchmarkUpdateScore(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	lb := randomLeaderboard(r, benchmarkPlayers, 240)
+	ri := newRankIndex(lb)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pos := r.Intn(len(lb))
+		ri.SetScore(lb, pos, r.Intn(241))
+		ri.Rank(lb[pos].Score)
+	}
+}
+
+// BenchmarkUpdateScoreSort is the cost of an update when sorting the whole leaderboard.
+func BenchmarkUpdateScoreSort(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	lb := randomLeaderboard(r, benchmarkPlayers, 240)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lb[r.Intn(len(lb))].Score = r.Intn(241)
+		lb.sort()
+	}
+}
+
+func BenchmarkRankedLeaderboard(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	lb := randomLeaderboard(r, benchmarkPlayers, 240)
+	ri := newRankIndex(lb)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ri.Ranked(lb)
+	}
+}
+
+func BenchmarkLoadMembers(b *testing.B) {
+	s := newFakeSession(0)
+	s.addMembers(benchmarkPlayers)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mc := newMemberCache()
+		if err := mc.Load(s, "guild"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
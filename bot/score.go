@@ -2,17 +2,19 @@ package bot
 
 import (
 	"fmt"
+	"image"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	R "github.com/ashyaa/birtho/render"
 	U "github.com/ashyaa/birtho/util"
+	DG "github.com/bwmarrin/discordgo"
 )
 
 func (b *Bot) GetUserScore(user string, serv Server) int {
 	res := 0
-	if _, ok := serv.Users[user]; !ok {
-		serv.Users[user] = make([]string, 0)
-	}
 	for _, itemID := range serv.Users[user] {
 		if item, ok := b.Items[itemID]; ok {
 			res += item.Points
@@ -21,15 +23,23 @@ func (b *Bot) GetUserScore(user string, serv Server) int {
 	return res
 }
 
+// GetUserScoreboard returns the scoreboard of the user, updating their score in the leaderboard
+// when they play and are not excluded from the game. The server is saved only when the leaderboard
+// changed.
 func (b *Bot) GetUserScoreboard(user string, serv Server) ScoreBoard {
-	serv = b.updateScore(user, serv)
-	b.SaveServer(serv)
-	var res ScoreBoard
-	for _, sb := range serv.Lb {
-		if sb.UID == user {
-			return sb
+	if _, ok := serv.Users[user]; ok && !serv.Excluded(user) {
+		var changed bool
+		if serv, changed = b.updateScore(user, serv); changed {
+			b.SaveServer(serv)
 		}
 	}
+	ri := b.rankIndex(serv)
+	pos, ok := ri.Position(serv.Lb, user)
+	if !ok {
+		return ScoreBoard{UID: user, Name: b.memberName(serv.ID, user)}
+	}
+	res := serv.Lb[pos]
+	res.Rank = rankString(ri.Rank(res.Score))
 	return res
 }
 
@@ -44,7 +54,16 @@ type Leaderboard []ScoreBoard
 
 // Sort the leaderboard by score in decreasing order, and updates the rank.
 func (lb Leaderboard) sort() {
-	sort.Slice(lb, func(i, j int) bool {
+	lb.sortBy(false)
+}
+
+// Sort the leaderboard by score, in increasing order if ascending, and updates the rank. Players
+// with the same score keep their order.
+func (lb Leaderboard) sortBy(ascending bool) {
+	sort.SliceStable(lb, func(i, j int) bool {
+		if ascending {
+			return lb[i].Score < lb[j].Score
+		}
 		return lb[i].Score > lb[j].Score
 	})
 	if len(lb) > 0 {
@@ -62,74 +81,100 @@ func (lb Leaderboard) sort() {
 	}
 }
 
-func (lb Leaderboard) Strings() []string {
-	res := []string{}
+// Formatter returns the header and the function rendering lines of the leaderboard, with columns
+// aligned for the whole leaderboard.
+func (lb Leaderboard) Formatter() (string, func(ScoreBoard) string) {
+	return lb.formatter(rankings[RankingPoints])
+}
+
+func (lb Leaderboard) formatter(ranking Ranking) (string, func(ScoreBoard) string) {
 	rankPlaces := len(fmt.Sprintf("%d", len(lb))) + 2 // + suffix 'st', 'nd', 'rd', 'th'
 	if rankPlaces < 4 {
 		rankPlaces = 4
 	}
-	header := "Rank     Points     User"
+	header := "Rank" + strings.Repeat(" ", 11-len(ranking.Column)) + ranking.Column + "     User"
 	if rankPlaces > 4 {
 		header = padLeft(header, rankPlaces-4)
 	}
-	res = append(res, header)
-	if len(lb) == 0 {
-		return res
-	}
-	for _, sb := range lb {
+	return header, func(sb ScoreBoard) string {
 		rank := sb.Rank
 		rank = padLeft(rank, rankPlaces-len(rank))
 
-		score := fmt.Sprintf("%d", sb.Score)
+		score := ranking.format(sb.Score)
 		score = padLeft(score, 11-len(score))
 
-		line := fmt.Sprintf("%s%s     %s", rank, score, sb.Name)
-		res = append(res, line)
+		return fmt.Sprintf("%s%s     %s", rank, score, sb.Name)
+	}
+}
+
+func (lb Leaderboard) Strings() []string {
+	header, line := lb.Formatter()
+	res := []string{header}
+	for _, sb := range lb {
+		res = append(res, line(sb))
 	}
 	return res
 }
 
-// Update leaderboard names with current user nicknames if any, else username
-func (b *Bot) updateLBNames(serv Server) Server {
-	members, err := b.s.GuildMembers(serv.ID, "", 1000)
-	if err != nil {
-		return serv
+// Update leaderboard names with current user nicknames if any, else username. Players who left
+// the server keep their last known name. Returns true if a name changed.
+func (b *Bot) updateLBNames(serv Server) bool {
+	if !b.loadMembers(serv.ID) {
+		return false
 	}
-	for _, member := range members {
-		if _, ok := serv.Users[member.User.ID]; !ok {
-			continue
+	changed := false
+	for i, sb := range serv.Lb {
+		if serv.Hidden(sb.UID) {
+			continue // hidden players keep their last known name
 		}
-		for i := range serv.Lb {
-			if serv.Lb[i].UID == member.User.ID {
-				serv.Lb[i].Name = U.MemberName(member)
-				break
-			}
+		if name, ok := b.members.Name(serv.ID, sb.UID); ok && name != sb.Name {
+			serv.Lb[i].Name = name
+			changed = true
 		}
 	}
-	return serv
+	return changed
 }
 
-func (b *Bot) updateScore(uid string, serv Server) Server {
+// loadMembers caches the members of the guild if they were not yet. Returns false on failure.
+func (b *Bot) loadMembers(gid string) bool {
+	if b.members.Loaded(gid) {
+		return true
+	}
+	if err := b.members.Load(b.s, gid); err != nil {
+		b.WarnE(err, "loading members of server %s", gid)
+		return false
+	}
+	return true
+}
+
+// updateScore updates the score of the player in the stored leaderboard, and returns whether it
+// changed.
+func (b *Bot) updateScore(uid string, serv Server) (Server, bool) {
 	score := b.GetUserScore(uid, serv)
-	found := false
-	for i := range serv.Lb {
-		if serv.Lb[i].UID != uid {
-			continue
-		}
-		found = true
-		if serv.Lb[i].Score == score {
-			return serv
+	ri := b.rankIndex(serv)
+	pos, ok := ri.Position(serv.Lb, uid)
+	if !ok {
+		if _, stale := ri.positions[uid]; stale {
+			b.dropRankIndex(serv.ID)
+			return b.updateScore(uid, serv)
 		}
-		serv.Lb[i].Score = score
-		break
+		serv.Lb = ri.Add(serv.Lb, ScoreBoard{UID: uid, Name: b.memberName(serv.ID, uid), Score: score})
+		return serv, true
+	}
+	if serv.Lb[pos].Score == score {
+		return serv, false
 	}
-	if !found {
-		serv.Lb = append(serv.Lb, ScoreBoard{UID: uid, Score: score})
-		serv = b.updateLBNames(serv)
-		return serv
+	ri.SetScore(serv.Lb, pos, score)
+	return serv, true
+}
+
+// rankNumber returns the number of a rank string, or 0 if it is invalid.
+func rankNumber(rank string) int {
+	res, err := strconv.Atoi(strings.TrimRight(rank, "stndrh"))
+	if err != nil {
+		return 0
 	}
-	serv.Lb.sort()
-	return serv
+	return res
 }
 
 func rankString(n int) string {
@@ -162,38 +207,128 @@ func padLeft(s string, padding int) string {
 	return res
 }
 
+// getLeaderBoard returns the ranked leaderboard of the server, adding the players missing from the
+// stored leaderboard and removing the ones who no longer play.
 func (b *Bot) getLeaderBoard(serv Server) Leaderboard {
-	if len(serv.Lb) == 0 || len(serv.Users) != len(serv.Lb) {
+	changed := false
+	if len(serv.Lb) > len(serv.Users) {
 		lb := Leaderboard{}
-		users := serv.Users
-		for usr := range users {
-			lb = append(lb, ScoreBoard{usr, "", b.GetUserScore(usr, serv), ""})
+		for _, sb := range serv.Lb {
+			if _, ok := serv.Users[sb.UID]; ok {
+				lb = append(lb, sb)
+			}
 		}
-		lb.sort()
 		serv.Lb = lb
+		b.dropRankIndex(serv.ID)
+		changed = true
+	}
+	if len(serv.Lb) < len(serv.Users) {
+		b.loadMembers(serv.ID) // name the new players without requesting them one by one
+		ri := b.rankIndex(serv)
+		for uid := range serv.Users {
+			if _, ok := ri.Position(serv.Lb, uid); !ok {
+				serv, _ = b.updateScore(uid, serv)
+			}
+		}
+		changed = true
+	}
+	ri := b.rankIndex(serv)
+	if b.updateLBNames(serv) {
+		ri.sorted = nil
+		changed = true
 	}
-	serv = b.updateLBNames(serv)
-	b.SaveServer(serv)
-	return serv.Lb
+	if changed {
+		b.SaveServer(serv)
+	}
+	return ri.Ranked(serv.Lb)
 }
 
-func ShowLeaderboard(b *Bot, p CommandParameters) {
-	lb := b.getLeaderBoard(p.S).Strings()
-	menu := NewMenu(lb[1:], 10, p.CID, p.GID)
-	menu.SetHeader(lb[0])
-	menu.SetTitle("Server leaderboard")
+// Kinds of the persistent menus
+const (
+	LeaderboardMenu = "lb"
+	ScoreboardMenu  = "sb"
+)
+
+// rankingMenu returns a paginated menu showing the leaderboard, as an image unless text menus
+// are configured.
+func (b *Bot) rankingMenu(lb Leaderboard, ranking Ranking, title, cID, gID string) *Menu[ScoreBoard] {
+	menu := NewMenu(lb, 10, cID, gID)
+	menu.SetLines(lb.formatter(ranking))
+	menu.SetLocator(func(sb ScoreBoard, uid string) bool {
+		return sb.UID == uid
+	})
+	menu.SetPageLabel(func(page int, sbs []ScoreBoard) string {
+		if len(sbs) == 0 {
+			return fmt.Sprintf("Page %d", page)
+		}
+		return fmt.Sprintf("Page %d: %s - %s", page, sbs[0].Rank, sbs[len(sbs)-1].Rank)
+	})
+	if !b.conf.TextMenus {
+		menu.SetImage("leaderboard.png", func(_ int, sbs []ScoreBoard) (image.Image, error) {
+			rows := []R.LeaderboardRow{}
+			for _, sb := range sbs {
+				rows = append(rows, R.LeaderboardRow{
+					Rank:     sb.Rank,
+					Position: rankNumber(sb.Rank),
+					Name:     sb.Name,
+					Score:    sb.Score,
+					Value:    ranking.format(sb.Score),
+				})
+			}
+			return R.Leaderboard(title, ranking.Column, rows)
+		}, true)
+	}
+	menu.SetTitle(title)
+	return menu
+}
+
+// leaderboardMenu returns the server leaderboard for the given window and ranking. All-time points
+// come from the stored leaderboard, other rankings from the recorded grabs.
+func (b *Bot) leaderboardMenu(serv Server, cID, window, rankingName string) *Menu[ScoreBoard] {
+	ranking, ok := rankings[rankingName]
+	if !ok {
+		ranking, rankingName = rankings[RankingPoints], RankingPoints
+	}
+	if window == WindowAll && rankingName == RankingPoints {
+		return b.allTimeLeaderboardMenu(serv, cID)
+	}
+	since := serv.WindowStart(window, time.Now())
+	lb := serv.visible(b.windowLeaderboard(serv, since, ranking), ranking.Ascending)
+	menu := b.rankingMenu(lb, ranking, ranking.Title+windowLabel(window), cID, serv.ID)
+	subtitle := fmt.Sprintf("Players: `%d`", len(lb))
+	if !since.IsZero() {
+		subtitle += "\u2060 \u2060 \u2060 \u2060 \u2060 Since " + U.Timestamp(since)
+	}
+	menu.SetSubtitle(subtitle)
+	menu.SetKind(LeaderboardMenu, window, rankingName)
+	return menu
+}
+
+func (b *Bot) allTimeLeaderboardMenu(serv Server, cID string) *Menu[ScoreBoard] {
+	menu := b.rankingMenu(serv.visible(b.getLeaderBoard(serv), false), rankings[RankingPoints], "Server leaderboard", cID, serv.ID)
 	subtitle := fmt.Sprintf("Total number of points: `%d`", b.TotalPoints())
-	if p.S.G.Finished {
-		subtitle += "\u2060 \u2060 \u2060 \u2060 \u2060 Winner: " + U.BuildUserTag(p.S.G.Winner)
+	if serv.G.Finished {
+		subtitle += "\u2060 \u2060 \u2060 \u2060 \u2060 Winner: " + U.BuildUserTag(serv.G.Winner)
 	}
 	menu.SetSubtitle(subtitle)
-	err := menu.Send(b.s, p.I)
+	menu.SetKind(LeaderboardMenu)
+	return menu
+}
+
+func ShowLeaderboard(b *Bot, p CommandParameters) {
+	window, ok := p.Options["window"].(string)
+	if !ok {
+		window = WindowAll
+	}
+	ranking, ok := p.Options["ranking"].(string)
+	if !ok {
+		ranking = RankingPoints
+	}
+	menu := b.leaderboardMenu(p.S, p.CID, window, ranking)
+	err := b.sendMenu(menu, p.I)
 	if err != nil {
-		b.Error("creating menu: %s", err.Error())
-		return
+		p.Log.ErrorE(err, "creating menu")
 	}
-	b.Menus[menu.ID()] = menu
-	time.AfterFunc(time.Duration(61)*time.Second, purgeMenus(b))
 }
 
 func (b *Bot) getItemList(usr string, serv Server) []string {
@@ -205,42 +340,70 @@ func (b *Bot) getItemList(usr string, serv Server) []string {
 	return userItems
 }
 
-func formatItemList(monsters []Monster, playerItems []string) (res []string) {
-	has := U.ToHashMap(playerItems)
+// itemField returns the embed field listing the items of a monster, hiding the items the player
+// does not have.
+func itemField(monster Monster, has map[string]bool) *DG.MessageEmbedField {
+	lines := []string{}
+	for _, item := range monster.Items {
+		lines = append(lines, item.Description(!has[item.ID]))
+	}
+	return &DG.MessageEmbedField{Name: monster.Name, Value: strings.Join(lines, "\n")}
+}
+
+// collection returns the monsters to draw in a player's collection image.
+func (b *Bot) collection(monsters []Monster, has map[string]bool) []R.CollectionMonster {
+	res := []R.CollectionMonster{}
 	for _, monster := range monsters {
-		res = append(res, monster.Name, "")
+		cm := R.CollectionMonster{Name: monster.Name, Thumbnail: b.thumbnails.Get(monster.URL)}
 		for _, item := range monster.Items {
-			_, found := has[item.ID]
-			res = append(res, item.Description(!found))
+			cm.Items = append(cm.Items, R.CollectionItem{Owned: has[item.ID], Rarity: item.Rarity()})
 		}
+		res = append(res, cm)
 	}
 	return res
 }
 
 const hint = "🔸Common\u2060 \u2060 \u2060 \u2060 \u2060 🟠Uncommon\u2060 \u2060 \u2060 \u2060 \u2060 🟧Rare"
 
-func ShowScore(b *Bot, p CommandParameters) {
-	sb := b.GetUserScoreboard(p.UID, p.S)
+func (b *Bot) scoreboardMenu(serv Server, uid, cID string) *Menu[Monster] {
+	sb := b.GetUserScoreboard(uid, serv)
 	monsters := b.SortedMonsters()
 	images := []string{}
 	for _, m := range monsters {
 		images = append(images, m.URL)
 	}
-	menu := NewMenu(
-		formatItemList(monsters, b.getItemList(p.UID, p.S)),
-		5, p.CID, p.GID)
+	has := U.ToHashMap(b.getItemList(uid, serv))
+	menu := NewMenu(monsters, 1, cID, serv.ID)
+	menu.SetFields(func(m Monster) *DG.MessageEmbedField {
+		return itemField(m, has)
+	})
+	menu.SetPageLabel(func(page int, monsters []Monster) string {
+		return monsters[0].Name
+	})
+	if !b.conf.TextMenus {
+		menu.SetImage("collection.png", func(page int, _ []Monster) (image.Image, error) {
+			return R.Collection(sb.Name+"'s collection", b.collection(monsters, has), page-1)
+		}, false)
+	}
 	menu.SetTitle(sb.Name + "'s scoreboard")
 	menu.SetImages(images)
-	infos := fmt.Sprintf("Items: `%d/%d`", len(p.S.Users[p.UID]), len(b.Items))
+	infos := fmt.Sprintf("Items: `%d/%d`", len(serv.Users[uid]), len(b.Items))
 	infos += "\u2060 \u2060 \u2060 \u2060 \u2060 " + fmt.Sprintf("Points: `%d`", sb.Score)
 	infos += "\u2060 \u2060 \u2060 \u2060 \u2060 " + fmt.Sprintf("Rank: `%s`", sb.Rank)
+	if b.conf.Pity.Enabled() {
+		infos += "\n" + b.conf.Pity.describe(serv.Pity[uid])
+	}
 	menu.SetSubtitle(infos)
 	menu.SetFooter(hint)
-	err := menu.Send(b.s, p.I)
+	menu.SetOwner(uid, true)
+	menu.SetKind(ScoreboardMenu, uid)
+	return menu
+}
+
+func ShowScore(b *Bot, p CommandParameters) {
+	menu := b.scoreboardMenu(p.S, p.UID, p.CID)
+	err := b.sendMenu(menu, p.I)
 	if err != nil {
-		b.Error("creating menu: %s", err.Error())
-		return
+		p.Log.ErrorE(err, "creating menu")
 	}
-	b.Menus[menu.ID()] = menu
-	time.AfterFunc(61*time.Second, purgeMenus(b))
 }
@@ -1,6 +1,7 @@
 package bot
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -51,3 +52,37 @@ func TestLeaderBoardSort(t *testing.T) {
 	lb.sort()
 	a.Equal(exp, lb)
 }
+
+func TestUserScoreboard(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	serv := b.NewServer("guild")
+	serv.Users["player"] = []string{"m1i1"}
+	b.SaveServer(serv)
+
+	a.Equal(ScoreBoard{UID: "player", Name: b.memberName("guild", "player"), Score: 1, Rank: "1st"}, b.GetUserScoreboard("player", serv))
+	serv = b.GetServer("guild")
+	// Looking up a user who does not play leaves the leaderboard as it is
+	a.Equal(ScoreBoard{UID: "visitor", Name: b.memberName("guild", "visitor")}, b.GetUserScoreboard("visitor", serv))
+	serv = b.GetServer("guild")
+	lb := []string{}
+	for _, sb := range serv.Lb {
+		lb = append(lb, sb.UID)
+	}
+	users := []string{}
+	for uid := range serv.Users {
+		users = append(users, uid)
+	}
+	sort.Strings(users)
+	a.Equal(users, lb)
+
+	// Scoreboard pages rebuilt with an unchanged score do not save the server
+	stored := b.GetServer("guild")
+	stored.Prefix = "!"
+	b.SaveServer(stored)
+	a.Equal(1, b.GetUserScoreboard("player", serv).Score)
+	a.Equal("!", b.GetServer("guild").Prefix)
+	serv.Users["player"] = append(serv.Users["player"], "m1i3")
+	a.Equal(11, b.GetUserScoreboard("player", serv).Score)
+	a.Equal(DefaultPrefix, b.GetServer("guild").Prefix)
+}
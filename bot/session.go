@@ -0,0 +1,23 @@
+package bot
+
+import DG "github.com/bwmarrin/discordgo"
+
+// Session is the subset of the Discord REST API used by the bot. It is implemented by
+// *discordgo.Session, and can be faked in tests.
+type Session interface {
+	ChannelMessage(channelID, messageID string, options ...DG.RequestOption) (*DG.Message, error)
+	ChannelMessageSend(channelID, content string, options ...DG.RequestOption) (*DG.Message, error)
+	ChannelMessageSendComplex(channelID string, data *DG.MessageSend, options ...DG.RequestOption) (*DG.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *DG.MessageEmbed, options ...DG.RequestOption) (*DG.Message, error)
+	ChannelMessageEditComplex(m *DG.MessageEdit, options ...DG.RequestOption) (*DG.Message, error)
+	ChannelMessageEditEmbed(channelID, messageID string, embed *DG.MessageEmbed, options ...DG.RequestOption) (*DG.Message, error)
+	MessageReactionAdd(channelID, messageID, emojiID string, options ...DG.RequestOption) error
+	InteractionRespond(interaction *DG.Interaction, resp *DG.InteractionResponse, options ...DG.RequestOption) error
+	InteractionResponse(interaction *DG.Interaction, options ...DG.RequestOption) (*DG.Message, error)
+	GuildMember(guildID, userID string, options ...DG.RequestOption) (*DG.Member, error)
+	GuildMembers(guildID, after string, limit int, options ...DG.RequestOption) ([]*DG.Member, error)
+	GuildChannels(guildID string, options ...DG.RequestOption) ([]*DG.Channel, error)
+	UserChannelCreate(recipientID string, options ...DG.RequestOption) (*DG.Channel, error)
+}
+
+var _ Session = (*DG.Session)(nil)
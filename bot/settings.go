@@ -79,17 +79,26 @@ func Info(b *Bot, p CommandParameters) {
 	}
 	msg.AddField("Game status", game)
 
-	if p.S.G.On {
-		msg.AddField("Next spawn", U.Timestamp(p.S.G.NextSpawn))
-	}
-
 	// Show configured cooldown
 	maxDelay := p.S.G.MinDelay + time.Duration(p.S.G.VariableDelay-1)*time.Second
 	msg.AddField("Cooldown", fmt.Sprintf("`%v - %v`", p.S.G.MinDelay, maxDelay))
 
+	// Show the spawn policy
+	msg.AddField("Spawn policy", fmt.Sprintf("`%s`: %s", p.S.G.PolicyName(), p.S.G.Policy().Description()))
+
 	// Show configured monster stay time
 	msg.AddField("Monster stay time", fmt.Sprintf("`%v`", p.S.G.StayTime))
 
+	// Show the global leaderboard participation
+	global := "off"
+	if p.S.Global {
+		global = "on"
+		if p.S.Pack != b.pack {
+			global += ", collections from another item pack"
+		}
+	}
+	msg.AddField("Global leaderboard", fmt.Sprintf("`%s`", global))
+
 	// Show configured prefix
 	msg.AddField("Prefix", fmt.Sprintf("`%s`", p.S.Prefix))
 
@@ -104,15 +113,54 @@ func Info(b *Bot, p CommandParameters) {
 	}
 	msg.AddField("Admins", admins)
 
-	// Show the configured list of spawn channels
-	channelTags := []string{}
-	for _, cid := range p.S.Channels {
-		channelTags = append(channelTags, U.BuildChannelTag(cid))
+	// Show where the players suspected of cheating are flagged
+	adminChannel := "None"
+	if p.S.AdminChannel != "" {
+		adminChannel = U.BuildChannelTag(p.S.AdminChannel)
+	}
+	msg.AddField("Admin channel", adminChannel)
+
+	// Show the state of each spawn channel
+	if len(p.S.Channels) == 0 {
+		msg.AddField("Channels", "None")
 	}
-	channels := "None"
-	if len(p.S.Channels) != 0 {
-		channels = strings.Join(channelTags, ", ")
+	for i, cid := range p.S.Channels {
+		if i == maxChannelFields {
+			msg.AddField("Channels", fmt.Sprintf("and %d more", len(p.S.Channels)-i))
+			break
+		}
+		msg.AddField("Channel", b.channelInfo(p.S, cid))
 	}
-	msg.AddField("Channels", channels)
 	SendEmbed(b.s, p.I, p.CID, msg.MessageEmbed, nil)
 }
+
+// maxChannelFields is the number of channels shown by the info command, to stay within the
+// 25 fields of an embed.
+const maxChannelFields = 12
+
+// channelInfo describes the spawn state of a channel.
+func (b *Bot) channelInfo(serv Server, cid string) string {
+	lines := []string{U.BuildChannelTag(cid)}
+	state, ok := serv.G.ChannelStates[cid]
+	if !ok {
+		state = newChannelState(time.Time{})
+	}
+	if spawn, ok := serv.G.Monsters[cid]; ok {
+		lines = append(lines, fmt.Sprintf("Visitor: **%s**", b.Monsters[spawn.ID].Name))
+	} else if serv.G.On && state.NextSpawn.After(time.Now()) {
+		lines = append(lines, "Next spawn: "+U.Timestamp(state.NextSpawn))
+	} else if serv.G.On {
+		lines = append(lines, "Next spawn: `ready`")
+	}
+	switch serv.G.PolicyName() {
+	case PolicyRate:
+		lines = append(lines, fmt.Sprintf("Spawn rate: `%d%%`", state.SpawnRate))
+	case PolicyActivity:
+		lines = append(lines, fmt.Sprintf("Activity: `%.1f`", state.Activity.Score))
+	}
+	if last := state.LastMessages[len(state.LastMessages)-1]; last.Author != "" {
+		lines = append(lines, "Last message: "+U.Timestamp(last.Time))
+	}
+	lines = append(lines, "Monsters: "+b.describePool(state.Pool))
+	return strings.Join(lines, "\n")
+}
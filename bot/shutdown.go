@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	DG "github.com/bwmarrin/discordgo"
+	embed "github.com/clinet/discordgo-embed"
+)
+
+// enter registers an in-progress handler. It returns false once the bot is shutting down, in
+// which case the handler must not run. Every successful call must be followed by a call to leave.
+func (b *Bot) enter() bool {
+	b.closingMutex.Lock()
+	defer b.closingMutex.Unlock()
+	if b.closing {
+		return false
+	}
+	b.handlers.Add(1)
+	return true
+}
+
+func (b *Bot) leave() {
+	b.handlers.Done()
+}
+
+// Stop stops accepting commands, waits for in-progress handlers, resolves active spawns and the
+// menus that expire, then closes the database and the session. Waiting for the handlers may take
+// half of the configured shutdown deadline, resolving the rest of it. The database is left open if
+// handlers are still running, they would fail in the middle of their writes. Menus that never
+// expire are left as they are: they are rebuilt from the database once the bot restarts.
+func (b *Bot) Stop() {
+	b.closingMutex.Lock()
+	b.closing = true
+	b.closingMutex.Unlock()
+	b.StopHealth()
+
+	deadline := b.conf.ShutdownDeadline
+	if deadline <= 0 {
+		deadline = DefaultShutdownDeadline
+	}
+	end := time.Now().Add(deadline)
+
+	b.Info("waiting for in-progress commands")
+	done := make(chan struct{})
+	go func() {
+		b.handlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(deadline / 2):
+		b.Warn("shutdown deadline reached while waiting for in-progress commands")
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), end)
+	defer cancel()
+	b.resolveSpawns(ctx)
+	b.Menus.Close(ctx)
+	b.stopBackups()
+
+	select {
+	case <-done:
+		b.Info("closing database")
+		if err := b.db.Close(); err != nil {
+			b.ErrorE(err, "closing database")
+		}
+	default:
+		b.Warn("commands still in progress, leaving the database open")
+	}
+	if b.ws != nil {
+		b.ws.Close()
+	}
+	b.Info("gracefully shutting down")
+}
+
+// resolveSpawns edits every active spawn message to show the visitor vanished, and removes the
+// spawns from the game. Servers locked by a command still running are skipped.
+func (b *Bot) resolveSpawns(ctx context.Context) {
+	servers, err := b.Servers()
+	if err != nil {
+		b.ErrorE(err, "listing servers")
+		return
+	}
+	count := 0
+	for _, serv := range servers {
+		if len(serv.G.Monsters) == 0 {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		unlock, ok := b.tryLockGuild(serv.ID)
+		if !ok {
+			b.Warn("server %s busy, its spawns are left active", serv.ID)
+			continue
+		}
+		count += b.resolveServerSpawns(ctx, b.GetServer(serv.ID))
+		unlock()
+	}
+	if ctx.Err() != nil {
+		b.Warn("shutdown deadline reached, %d spawns resolved", count)
+		return
+	}
+	b.Info("resolved %d active spawns", count)
+}
+
+// resolveServerSpawns resolves the spawns of the server until the context is done, and saves the
+// server. Returns the number of spawns resolved.
+func (b *Bot) resolveServerSpawns(ctx context.Context, serv Server) int {
+	count := 0
+	defer func() {
+		if count > 0 {
+			b.SaveServer(serv)
+		}
+	}()
+	for cid, spawn := range serv.G.Monsters {
+		if ctx.Err() != nil {
+			return count
+		}
+		b.vanish(ctx, cid, spawn)
+		delete(serv.G.Monsters, cid)
+		count++
+	}
+	return count
+}
+
+// vanish edits the message of the spawn to show the visitor vanished.
+func (b *Bot) vanish(ctx context.Context, cid string, spawn MonsterSpawn) {
+	monster := b.Monsters[spawn.ID]
+	edit := DG.NewMessageEdit(cid, spawn.Message).SetEmbed(embed.NewEmbed().
+		SetTitle("The visitor vanished.").
+		SetDescription(fmt.Sprintf("**%s** vanished into thin air...", monster.Name)).
+		SetColor(0x555555).MessageEmbed)
+	if _, err := b.s.ChannelMessageEditComplex(edit, DG.WithContext(ctx)); err != nil {
+		b.WarnE(err, "resolving spawn %s in channel %s", spawn.Message, cid)
+	}
+}
+
+// afterFunc is time.AfterFunc for callbacks that must not run once the bot is shutting down.
+func (b *Bot) afterFunc(d time.Duration, f func()) *time.Timer {
+	return time.AfterFunc(d, func() {
+		if !b.enter() {
+			return
+		}
+		defer b.leave()
+		f()
+	})
+}
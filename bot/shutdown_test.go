@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdown(t *testing.T) {
+	spawn := func(b *Bot, gid string, n int) {
+		serv := b.NewServer(gid)
+		for i := 0; i < n; i++ {
+			serv.G.Monsters[fmt.Sprintf("30000000000000000%d", i)] = MonsterSpawn{ID: "1", Message: snowflake()}
+		}
+		b.SaveServer(serv)
+	}
+
+	t.Run("deadline", func(t *testing.T) {
+		a := assert.New(t)
+		b := newTestBot(t, newFakeSession(50*time.Millisecond))
+		spawn(b, "guild", 5)
+		ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+		defer cancel()
+		b.resolveSpawns(ctx)
+		left := len(b.GetServer("guild").G.Monsters)
+		a.Positive(left)
+		a.Less(left, 5)
+	})
+
+	t.Run("busy", func(t *testing.T) {
+		a := assert.New(t)
+		b := newTestBot(t, newFakeSession(0))
+		spawn(b, "busy", 2)
+		spawn(b, "idle", 2)
+		unlock := b.lockGuild("busy")
+		b.resolveSpawns(context.Background())
+		unlock()
+		a.Len(b.GetServer("busy").G.Monsters, 2)
+		a.Empty(b.GetServer("idle").G.Monsters)
+	})
+
+	t.Run("running handler", func(t *testing.T) {
+		a := assert.New(t)
+		b := newTestBot(t, newFakeSession(0))
+		b.conf.ShutdownDeadline = 100 * time.Millisecond
+		spawn(b, "guild", 2)
+		a.True(b.enter())
+		start := time.Now()
+		b.Stop()
+		a.Less(time.Since(start), time.Second)
+		// The database stays open for the handler
+		serv, err := b.FindServer("guild")
+		a.NoError(err)
+		a.Empty(serv.G.Monsters)
+		b.leave()
+	})
+}
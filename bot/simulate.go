@@ -0,0 +1,290 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+	LR "github.com/sirupsen/logrus"
+)
+
+// simulationChannel is the only spawn channel of the simulated servers.
+const simulationChannel = "simulation"
+
+// Simulation describes the games played by Simulate, and the chat activity of their players.
+type Simulation struct {
+	Runs            int           // number of simulated games
+	Duration        time.Duration // simulated time of each game
+	Players         int           // number of active players, chatting evenly
+	MessagesPerHour float64       // chat messages sent in the spawn channel, in average
+	Reaction        time.Duration // average time a watching player takes to greet a visitor
+	Attention       float64       // share of the players watching the channel when a visitor comes
+	MinDelay        time.Duration // minimum cooldown between two visitors
+	MaxDelay        time.Duration // maximum cooldown between two visitors
+	StayTime        time.Duration // time a visitor waits for a greeting
+	Policy          string        // spawn policy
+	Seed            int64         // seed of the random numbers, the same seed giving the same report
+}
+
+// DefaultSimulation returns a simulation of a month of a small but lively server, with the
+// default game settings.
+func DefaultSimulation() Simulation {
+	return Simulation{
+		Runs:            200,
+		Duration:        30 * 24 * time.Hour,
+		Players:         10,
+		MessagesPerHour: 60,
+		Reaction:        3 * time.Second,
+		Attention:       0.5,
+		MinDelay:        DefaultMinDelay,
+		MaxDelay:        DefaultMinDelay + (DefaultVariableDelay-1)*time.Second,
+		StayTime:        DefaultStayTime,
+		Policy:          DefaultSpawnPolicy,
+		Seed:            time.Now().UnixNano(),
+	}
+}
+
+func (s Simulation) validate() error {
+	switch {
+	case s.Runs <= 0:
+		return errors.New("the number of games must be positive")
+	case s.Duration <= 0:
+		return errors.New("the duration of the games must be positive")
+	case s.Players < 2:
+		return errors.New("visitors only come when at least two players chat")
+	case s.MessagesPerHour <= 0:
+		return errors.New("the number of messages per hour must be positive")
+	case s.Reaction <= 0:
+		return errors.New("the reaction time must be positive")
+	case s.Attention <= 0 || s.Attention > 1:
+		return errors.New("the attention must be in (0, 1]")
+	case s.MinDelay < 0 || s.MaxDelay < s.MinDelay:
+		return fmt.Errorf("invalid cooldown %v - %v", s.MinDelay, s.MaxDelay)
+	case s.StayTime <= 0:
+		return errors.New("the stay time must be positive")
+	case !U.Contains(policyNames, s.Policy):
+		return fmt.Errorf("unknown policy %s, expected one of: %s", s.Policy, strings.Join(policyNames, ", "))
+	}
+	return nil
+}
+
+// SimulationReport sums up the simulated games.
+type SimulationReport struct {
+	Simulation
+	Items       int             // number of items to collect
+	Visitors    float64         // visitors per game, in average
+	Grabs       float64         // items given per game, in average
+	Duplicates  float64         // duplicates per player and game, in average
+	Completions []time.Duration // time to the first complete collection of the games having one, sorted
+	Sizes       []float64       // share of the players owning each number of items at the end of a game
+}
+
+// run is the outcome of a simulated game.
+type run struct {
+	visitors   int
+	grabs      int
+	duplicates int
+	completion time.Duration // 0 when nobody completed their collection
+	sizes      []int         // number of players owning each number of items at the end
+}
+
+// Simulate plays Monte Carlo games with the monsters of the configuration.
+func Simulate(log *LR.Logger, conf Config, sim Simulation) (SimulationReport, error) {
+	return newBot(log, conf).simulate(sim)
+}
+
+func (b *Bot) simulate(sim Simulation) (SimulationReport, error) {
+	if err := sim.validate(); err != nil {
+		return SimulationReport{}, err
+	}
+	if len(b.Monsters) == 0 || len(b.Items) == 0 {
+		return SimulationReport{}, errors.New("no monster to simulate")
+	}
+
+	runs := make([]run, sim.Runs)
+	next := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				// Each game has its own generator, so that the report does not depend on scheduling
+				runs[i] = b.simulateGame(sim, U.NewSeededRNG(sim.Seed+int64(i)))
+			}
+		}()
+	}
+	for i := range runs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	res := SimulationReport{Simulation: sim, Items: len(b.Items), Sizes: make([]float64, len(b.Items)+1)}
+	for _, r := range runs {
+		res.Visitors += float64(r.visitors)
+		res.Grabs += float64(r.grabs)
+		res.Duplicates += float64(r.duplicates)
+		if r.completion > 0 {
+			res.Completions = append(res.Completions, r.completion)
+		}
+		for size, n := range r.sizes {
+			res.Sizes[size] += float64(n)
+		}
+	}
+	res.Visitors /= float64(sim.Runs)
+	res.Grabs /= float64(sim.Runs)
+	res.Duplicates /= float64(sim.Runs * sim.Players)
+	for size := range res.Sizes {
+		res.Sizes[size] /= float64(sim.Runs * sim.Players)
+	}
+	sort.Slice(res.Completions, func(i, j int) bool { return res.Completions[i] < res.Completions[j] })
+	return res, nil
+}
+
+// simulateGame plays a game on a server with a single spawn channel, following the same spawn
+// rules as Spawn. Messages follow a Poisson process, and visitors are greeted by the fastest
+// watching player, who picks the right greeting half of the time. Items are rolled as in Grab,
+// including the pity system of the configuration.
+func (b *Bot) simulateGame(sim Simulation, rng U.RNG) run {
+	start := time.Date(2020, time.October, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(sim.Duration)
+	serv := Server{
+		G: Game{
+			On:            true,
+			Monsters:      make(map[string]MonsterSpawn),
+			MinDelay:      sim.MinDelay,
+			VariableDelay: int((sim.MaxDelay-sim.MinDelay)/time.Second) + 1,
+			StayTime:      sim.StayTime,
+			SpawnPolicy:   sim.Policy,
+			ChannelStates: map[string]*ChannelState{simulationChannel: newChannelState(start)},
+		},
+		Channels: []string{simulationChannel},
+		Users:    make(map[string][]string),
+	}
+	res := run{sizes: make([]int, len(b.Items)+1)}
+
+	meanGap := float64(time.Hour) / sim.MessagesPerHour
+	leaves := start // departure of the current visitor
+	for now := start; ; {
+		now = now.Add(time.Duration(rng.ExpFloat64() * meanGap))
+		if now.After(end) {
+			break
+		}
+		author := rng.Intn(sim.Players)
+		if !now.Before(leaves) {
+			delete(serv.G.Monsters, simulationChannel)
+		}
+		if !serv.canSpawnAt(simulationChannel, now) {
+			continue
+		}
+		if !serv.G.Policy().Roll(rng, serv.G.Channel(simulationChannel), NewMessage(fmt.Sprint(author), now)) {
+			continue
+		}
+		monster := b.randomMonster(rng)
+		serv.G.Monsters[simulationChannel] = MonsterSpawn{ID: fmt.Sprint(monster.ID)}
+		serv.cooldownAt(rng, simulationChannel, now)
+		res.visitors++
+
+		player, reaction := sim.fastestPlayer(rng)
+		if player < 0 || reaction > sim.StayTime {
+			leaves = now.Add(sim.StayTime)
+			continue
+		}
+		leaves = now.Add(reaction)
+		if trickOrTreat(rng) != trickOrTreat(rng) {
+			continue // wrong greeting
+		}
+		uid := fmt.Sprint(player)
+		item := b.rollItem(rng, monster, &serv, uid)
+		res.grabs++
+		if U.Contains(serv.Users[uid], item.ID) {
+			res.duplicates++
+			continue
+		}
+		serv.Users[uid] = append(serv.Users[uid], item.ID)
+		if res.completion == 0 && len(serv.Users[uid]) == len(b.Items) {
+			res.completion = leaves.Sub(start)
+		}
+	}
+
+	for player := 0; player < sim.Players; player++ {
+		res.sizes[len(serv.Users[fmt.Sprint(player)])]++
+	}
+	return res
+}
+
+// fastestPlayer returns the first watching player to greet a visitor and their reaction time, or
+// -1 if no player is watching.
+func (sim Simulation) fastestPlayer(rng U.RNG) (int, time.Duration) {
+	player, fastest := -1, time.Duration(math.MaxInt64)
+	for i := 0; i < sim.Players; i++ {
+		if rng.Float64() >= sim.Attention {
+			continue
+		}
+		// Reaction times are spread around the average, without going under a quarter of it
+		reaction := time.Duration((1 + rng.NormFloat64()/3) * float64(sim.Reaction))
+		if reaction < sim.Reaction/4 {
+			reaction = sim.Reaction / 4
+		}
+		if reaction < fastest {
+			player, fastest = i, reaction
+		}
+	}
+	return player, fastest
+}
+
+// Percentile returns the time to the first complete collection reached by the given share of the
+// games having one.
+func (r SimulationReport) Percentile(p float64) time.Duration {
+	if len(r.Completions) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(p*float64(len(r.Completions)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return r.Completions[index]
+}
+
+// MeanCompletion returns the average time to the first complete collection of the games having one.
+func (r SimulationReport) MeanCompletion() time.Duration {
+	if len(r.Completions) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range r.Completions {
+		sum += d
+	}
+	return sum / time.Duration(len(r.Completions))
+}
+
+func (r SimulationReport) String() string {
+	round := func(d time.Duration) time.Duration { return d.Round(time.Minute) }
+	res := strings.Builder{}
+	fmt.Fprintf(&res, "%d games of %v with %d players, %.0f messages per hour, %v reaction time, %s policy (seed %d)\n",
+		r.Runs, r.Duration, r.Players, r.MessagesPerHour, r.Reaction, r.Policy, r.Seed)
+	fmt.Fprintf(&res, "Visitors per game: %.1f\n", r.Visitors)
+	duplicateRate := 0.0
+	if r.Grabs > 0 {
+		duplicateRate = r.Duplicates * float64(r.Players) / r.Grabs
+	}
+	fmt.Fprintf(&res, "Items given per game: %.1f, %.1f%% of them duplicates\n", r.Grabs, 100*duplicateRate)
+	fmt.Fprintf(&res, "Duplicates per player: %.2f\n", r.Duplicates)
+	fmt.Fprintf(&res, "Games with a complete collection: %d/%d\n", len(r.Completions), r.Runs)
+	if len(r.Completions) > 0 {
+		fmt.Fprintf(&res, "Time to the first complete collection: mean %v, median %v, 10%% %v, 90%% %v\n",
+			round(r.MeanCompletion()), round(r.Percentile(0.5)), round(r.Percentile(0.1)), round(r.Percentile(0.9)))
+	}
+	fmt.Fprintf(&res, "Collection sizes at the end of a game (out of %d items):\n", r.Items)
+	for size, share := range r.Sizes {
+		fmt.Fprintf(&res, "  %3d  %5.1f%%  %s\n", size, 100*share, strings.Repeat("#", int(math.Round(50*share))))
+	}
+	return res.String()
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulate(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBot(t, newFakeSession(0))
+	sim := DefaultSimulation()
+	sim.Runs = 50
+	sim.Duration = 7 * 24 * time.Hour
+	sim.Seed = 42
+
+	t.Run("report", func(t *testing.T) {
+		report, err := b.simulate(sim)
+		a.NoError(err)
+		a.Equal(3, report.Items)
+		a.Greater(report.Visitors, 0.0)
+		a.LessOrEqual(report.Grabs, report.Visitors)
+		// A week is plenty to collect three items
+		a.Len(report.Completions, sim.Runs)
+		a.LessOrEqual(report.Percentile(0.1), report.Percentile(0.9))
+		a.Greater(report.Duplicates, 0.0)
+		total := 0.0
+		for _, share := range report.Sizes {
+			total += share
+		}
+		a.InDelta(1, total, 1e-9)
+		a.Contains(report.String(), "Games with a complete collection: 50/50")
+	})
+
+	t.Run("server defaults", func(t *testing.T) {
+		// The simulator models the game new servers run with
+		game := b.NewServer("guild").G
+		defaults := DefaultSimulation()
+		a.Equal(game.MinDelay, defaults.MinDelay)
+		a.Equal(game.MinDelay+time.Duration(game.VariableDelay-1)*time.Second, defaults.MaxDelay)
+		a.Equal(game.StayTime, defaults.StayTime)
+	})
+
+	t.Run("same seed", func(t *testing.T) {
+		first, err := b.simulate(sim)
+		a.NoError(err)
+		second, err := b.simulate(sim)
+		a.NoError(err)
+		a.Equal(first, second)
+	})
+
+	t.Run("quiet server", func(t *testing.T) {
+		quiet := sim
+		quiet.MessagesPerHour = 1
+		report, err := b.simulate(quiet)
+		a.NoError(err)
+		busy, _ := b.simulate(sim)
+		a.Less(report.Visitors, busy.Visitors)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := sim
+		invalid.Players = 1
+		_, err := b.simulate(invalid)
+		a.Error(err)
+		invalid = sim
+		invalid.MaxDelay = sim.MinDelay - time.Second
+		_, err = b.simulate(invalid)
+		a.Error(err)
+	})
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+	DG "github.com/bwmarrin/discordgo"
+)
+
+// SpamOptions filter the messages feeding the spawn policies, so that visitors cannot be farmed
+// by spamming. The zero value lets every message count.
+type SpamOptions struct {
+	IgnoreBots bool `json:"ignore-bots,omitempty" yaml:"ignore-bots,omitempty"` // ignore bots and webhooks
+	MinLength  int  `json:"min-length,omitempty" yaml:"min-length,omitempty"`   // ignore shorter messages, in characters
+	// Decay weighs each message down by this factor for each of the last messages of the
+	// channel sent by the same author, if in (0, 1)
+	Decay         float64       `json:"decay,omitempty" yaml:"decay,omitempty"`
+	IgnoreRepeats bool          `json:"ignore-repeats,omitempty" yaml:"ignore-repeats,omitempty"` // ignore a message identical to one of the last messages of its author
+	MinAccountAge time.Duration `json:"min-account-age,omitempty" yaml:"min-account-age,omitempty"`
+	MinMemberAge  time.Duration `json:"min-member-age,omitempty" yaml:"min-member-age,omitempty"`
+}
+
+// digest returns a hash of the message content, ignoring case and spacing.
+func digest(content string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(strings.Join(strings.Fields(content), " "))))
+	return h.Sum32()
+}
+
+// withheld returns true if Discord did not send the content of the message, as for a bot without
+// the message content intent: a message always has a text, attachments, embeds or stickers.
+func withheld(msg *DG.Message) bool {
+	return msg.Content == "" && len(msg.Attachments) == 0 && len(msg.Embeds) == 0 && len(msg.StickerItems) == 0
+}
+
+// weigh returns how much a message counts for the spawn policies, from 0 (ignored) to 1, and the
+// reason why it counts less. It sets the digest of the history entry when repeats are ignored. The
+// filters on the content are skipped if the content was withheld.
+func (o SpamOptions) weigh(msg *DG.Message, entry *Message, history History) (float64, string) {
+	if o.IgnoreBots && (msg.Author.Bot || msg.WebhookID != "") {
+		return 0, "bot or webhook"
+	}
+	content := !withheld(msg)
+	if content && o.MinLength > 0 && len([]rune(strings.TrimSpace(msg.Content))) < o.MinLength {
+		return 0, "too short"
+	}
+	if o.MinAccountAge > 0 {
+		created, err := U.CreationTime(msg.Author.ID)
+		if err == nil && entry.Time.Sub(created) < o.MinAccountAge {
+			return 0, "account too recent"
+		}
+	}
+	if o.MinMemberAge > 0 && msg.Member != nil && !msg.Member.JoinedAt.IsZero() &&
+		entry.Time.Sub(msg.Member.JoinedAt) < o.MinMemberAge {
+		return 0, "member too recent"
+	}
+	if content && o.IgnoreRepeats {
+		entry.Digest = digest(msg.Content)
+		for _, previous := range history {
+			if previous.Author == entry.Author && previous.Digest == entry.Digest {
+				return 0, "repeated message"
+			}
+		}
+	}
+	if o.Decay > 0 && o.Decay < 1 {
+		recent := 0
+		for _, previous := range history {
+			if previous.Author == entry.Author {
+				recent++
+			}
+		}
+		if recent > 0 {
+			return math.Pow(o.Decay, float64(recent)), fmt.Sprintf("%d recent messages of the author", recent)
+		}
+	}
+	return 1, ""
+}
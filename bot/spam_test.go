@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	DG "github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAntiSpam(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	oldAccount := "100000000000000000" // created in 2015
+	history := newHistoryAt(now).Add(NewMessage(oldAccount, now))
+	weigh := func(o SpamOptions, msg *DG.Message) (float64, string) {
+		entry := NewMessage(msg.Author.ID, now)
+		return o.weigh(msg, &entry, history)
+	}
+	message := func(uid, content string) *DG.Message {
+		return &DG.Message{Author: &DG.User{ID: uid}, Content: content}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		weight, _ := weigh(SpamOptions{}, &DG.Message{Author: &DG.User{ID: snowflake(), Bot: true}})
+		a.Equal(1.0, weight)
+	})
+
+	t.Run("bots", func(t *testing.T) {
+		o := SpamOptions{IgnoreBots: true}
+		weight, reason := weigh(o, &DG.Message{Author: &DG.User{ID: oldAccount, Bot: true}})
+		a.Zero(weight)
+		a.Equal("bot or webhook", reason)
+		weight, _ = weigh(o, &DG.Message{Author: &DG.User{ID: oldAccount}, WebhookID: "1"})
+		a.Zero(weight)
+	})
+
+	t.Run("length", func(t *testing.T) {
+		o := SpamOptions{MinLength: 4}
+		weight, _ := weigh(o, message(oldAccount, " lol  "))
+		a.Zero(weight)
+		weight, _ = weigh(o, message(oldAccount, "hello"))
+		a.Equal(1.0, weight)
+		attachment := message(oldAccount, "")
+		attachment.Attachments = []*DG.MessageAttachment{{ID: "1"}}
+		weight, _ = weigh(o, attachment)
+		a.Zero(weight)
+
+		// Without the message content intent, the content is withheld
+		weight, _ = weigh(o, message(oldAccount, ""))
+		a.Equal(1.0, weight)
+	})
+
+	t.Run("ages", func(t *testing.T) {
+		o := SpamOptions{MinAccountAge: 24 * time.Hour, MinMemberAge: time.Hour}
+		weight, reason := weigh(o, message(snowflake(), "hello"))
+		a.Zero(weight)
+		a.Equal("account too recent", reason)
+		newcomer := message(oldAccount, "hello")
+		newcomer.Member = &DG.Member{JoinedAt: now.Add(-time.Minute)}
+		weight, reason = weigh(o, newcomer)
+		a.Zero(weight)
+		a.Equal("member too recent", reason)
+		newcomer.Member.JoinedAt = now.Add(-2 * time.Hour)
+		weight, _ = weigh(o, newcomer)
+		a.Equal(1.0, weight)
+	})
+
+	t.Run("decay", func(t *testing.T) {
+		o := SpamOptions{Decay: 0.5}
+		weight, _ := weigh(o, message(oldAccount, "hello"))
+		a.Equal(0.5, weight)
+		spammed := history.Add(NewMessage(oldAccount, now)).Add(NewMessage(oldAccount, now))
+		entry := NewMessage(oldAccount, now)
+		weight, _ = o.weigh(message(oldAccount, "hello"), &entry, spammed)
+		a.Equal(0.125, weight)
+		weight, _ = weigh(o, message("someone", "hello"))
+		a.Equal(1.0, weight)
+	})
+
+	t.Run("repeats", func(t *testing.T) {
+		o := SpamOptions{IgnoreRepeats: true}
+		entry := NewMessage(oldAccount, now)
+		weight, _ := o.weigh(message(oldAccount, "Spooky  time"), &entry, history)
+		a.Equal(1.0, weight)
+		repeated := history.Add(entry)
+		next := NewMessage(oldAccount, now)
+		weight, reason := o.weigh(message(oldAccount, "spooky time"), &next, repeated)
+		a.Zero(weight)
+		a.Equal("repeated message", reason)
+		other := NewMessage("someone", now)
+		weight, _ = o.weigh(message("someone", "spooky time"), &other, repeated)
+		a.Equal(1.0, weight)
+		withheld := NewMessage(oldAccount, now)
+		weight, _ = o.weigh(message(oldAccount, ""), &withheld, repeated.Add(withheld))
+		a.Equal(1.0, weight)
+	})
+
+	t.Run("spawn", func(t *testing.T) {
+		b := newTestBot(t, newFakeSession(0))
+		b.conf.AntiSpam = SpamOptions{IgnoreBots: true, MinLength: 3}
+		serv := b.NewServer("guild")
+		serv.G.On = true
+		serv.Channels = []string{"channel"}
+		b.SaveServer(serv)
+		handler := HandlerFromMessageCreate(b, b.command("spawn"))
+		robot := messageCreate("guild", "channel", "robot", "beep boop")
+		robot.Author.Bot = true
+		handler(nil, robot)
+		handler(nil, messageCreate("guild", "channel", "alice", "k"))
+		a.NotContains(b.GetServer("guild").G.ChannelStates, "channel")
+		handler(nil, messageCreate("guild", "channel", "alice", "hello"))
+		state := b.GetServer("guild").G.ChannelStates["channel"]
+		a.Equal("alice", state.LastMessages[HistoryDepth-1].Author)
+		a.Equal("", state.LastMessages[HistoryDepth-2].Author)
+	})
+}
@@ -0,0 +1,167 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+)
+
+// Spawn policies, deciding which messages make visitors come
+const (
+	PolicyRate     = "rate"
+	PolicyInterval = "interval"
+	PolicyPoisson  = "poisson"
+	PolicyActivity = "activity"
+
+	DefaultSpawnPolicy = PolicyRate
+)
+
+var policyNames = []string{PolicyRate, PolicyInterval, PolicyPoisson, PolicyActivity}
+
+const (
+	// PoissonInterval is the average time between two visitors of lively chats with the poisson
+	// policy, once the cooldown is over.
+	PoissonInterval = 10 * time.Minute
+	// ActivityHalfLife is the time it takes for the activity score of a channel to halve.
+	ActivityHalfLife = 10 * time.Minute
+	// ActivityScale is the activity score giving a visitor at each message.
+	ActivityScale = 200
+	// activeSpan is the longest span of the last messages of a lively chat.
+	activeSpan = 15 * time.Minute
+)
+
+// SpawnPolicy decides whether a message makes a visitor come. Its state is stored in the state of
+// each channel, so that policies are stateless and can be shared by all servers.
+type SpawnPolicy interface {
+	// Roll records a message sent in a spawn channel while a visitor can come, and returns true
+	// if the visitor comes.
+	Roll(rng U.RNG, c *ChannelState, msg Message) bool
+	// Description tells players how visitors come.
+	Description() string
+}
+
+var spawnPolicies = map[string]SpawnPolicy{
+	PolicyRate:     ratePolicy{},
+	PolicyInterval: intervalPolicy{},
+	PolicyPoisson:  poissonPolicy{},
+	PolicyActivity: activityPolicy{},
+}
+
+// Policy returns the spawn policy of the game.
+func (g Game) Policy() SpawnPolicy {
+	if policy, ok := spawnPolicies[g.SpawnPolicy]; ok {
+		return policy
+	}
+	return spawnPolicies[DefaultSpawnPolicy]
+}
+
+// PolicyName returns the name of the spawn policy of the game.
+func (g Game) PolicyName() string {
+	if _, ok := spawnPolicies[g.SpawnPolicy]; ok {
+		return g.SpawnPolicy
+	}
+	return DefaultSpawnPolicy
+}
+
+// ratePolicy raises the spawn rate by 0 to 2% at each message while several players chatted
+// within the last hour.
+type ratePolicy struct{}
+
+func (ratePolicy) Roll(rng U.RNG, c *ChannelState, msg Message) bool {
+	spawns := c.Spawns(rng)
+	c.LastMessages = c.LastMessages.Add(msg)
+	if spawns {
+		c.ResetSpawnRate()
+	} else {
+		c.raiseSpawnRate(rng)
+	}
+	return spawns
+}
+
+func (ratePolicy) Description() string {
+	return "the chance of a visitor rises with each message while several players chat"
+}
+
+// intervalPolicy brings a visitor with the first message after each cooldown.
+type intervalPolicy struct{}
+
+func (intervalPolicy) Roll(_ U.RNG, c *ChannelState, msg Message) bool {
+	c.LastMessages = c.LastMessages.Add(msg)
+	return true
+}
+
+func (intervalPolicy) Description() string {
+	return "a visitor comes with the first message after each cooldown"
+}
+
+// poissonPolicy brings visitors as a Poisson process of mean PoissonInterval over the time the
+// chat is lively, that is when several players sent the last messages within activeSpan.
+type poissonPolicy struct{}
+
+func (poissonPolicy) Roll(rng U.RNG, c *ChannelState, msg Message) bool {
+	since := c.LastMessages[HistoryDepth-1].Time
+	if c.NextSpawn.After(since) {
+		since = c.NextSpawn
+	}
+	c.LastMessages = c.LastMessages.Add(msg)
+	if !c.LastMessages.HasSeveralAuthors() || c.LastMessages.Span() > activeSpan {
+		return false
+	}
+	elapsed := msg.Time.Sub(since)
+	if elapsed <= 0 {
+		return false
+	}
+	// Chance of at least one arrival since the previous message
+	return rng.Float64() < 1-math.Exp(-float64(elapsed)/float64(PoissonInterval))
+}
+
+func (poissonPolicy) Description() string {
+	return fmt.Sprintf("visitors come every %v in average while several players chat", PoissonInterval)
+}
+
+// ActivityScore is the activity of a channel: each message adds a point, or two when its author
+// replies to someone else, and the score halves every ActivityHalfLife.
+type ActivityScore struct {
+	Score   float64
+	Updated time.Time
+}
+
+// activityPolicy scores the activity of the channel, and brings a visitor at each message with
+// a chance growing with the score of the channel.
+type activityPolicy struct{}
+
+func (activityPolicy) Roll(rng U.RNG, c *ChannelState, msg Message) bool {
+	activity := c.Activity
+	elapsed := msg.Time.Sub(activity.Updated)
+	if elapsed > 0 {
+		activity.Score *= math.Exp2(-float64(elapsed) / float64(ActivityHalfLife))
+	}
+	activity.Score++
+	if last := c.LastMessages[HistoryDepth-1].Author; last != "" && last != msg.Author {
+		activity.Score++
+	}
+	activity.Updated = msg.Time
+	c.LastMessages = c.LastMessages.Add(msg)
+
+	spawns := rng.Float64()*ActivityScale < activity.Score
+	if spawns {
+		activity.Score = 0
+	}
+	c.Activity = activity
+	return spawns
+}
+
+func (activityPolicy) Description() string {
+	return "the chance of a visitor grows with the recent activity of the channel"
+}
+
+func SetPolicy(b *Bot, p CommandParameters) {
+	name := p.Options["policy"].(string)
+	p.S.G.SpawnPolicy = name
+	b.SaveServer(p.S)
+
+	msg := fmt.Sprintf("Spawn policy set to `%s`: %s.", name, p.S.G.Policy().Description())
+	SendText(b.s, p.I, p.CID, msg)
+}
@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	U "github.com/ashyaa/birtho/util"
+	"github.com/stretchr/testify/assert"
+)
+
+var streamStart = time.Date(2020, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+// chatMessage is a message sent in a channel.
+type chatMessage struct {
+	cid string
+	Message
+}
+
+// chat returns n messages of a channel sent every gap by the given authors in turn.
+func chat(cid string, n int, gap time.Duration, authors ...string) []chatMessage {
+	res := []chatMessage{}
+	for i := 0; i < n; i++ {
+		res = append(res, chatMessage{cid, NewMessage(authors[i%len(authors)], streamStart.Add(time.Duration(i+1)*gap))})
+	}
+	return res
+}
+
+// merge returns the messages of both streams sorted by time.
+func merge(first, second []chatMessage) []chatMessage {
+	res := []chatMessage{}
+	for len(first) > 0 || len(second) > 0 {
+		if len(second) == 0 || (len(first) > 0 && !first[0].Time.After(second[0].Time)) {
+			res, first = append(res, first[0]), first[1:]
+		} else {
+			res, second = append(res, second[0]), second[1:]
+		}
+	}
+	return res
+}
+
+// play feeds the messages to a policy as Spawn does, with a fixed cooldown after each visitor,
+// and returns the number of visitors of each channel.
+func play(policy string, msgs []chatMessage, cooldown time.Duration) map[string]int {
+	serv := Server{
+		G: Game{
+			On:            true,
+			Monsters:      make(map[string]MonsterSpawn),
+			MinDelay:      cooldown,
+			VariableDelay: 1,
+			SpawnPolicy:   policy,
+			ChannelStates: map[string]*ChannelState{
+				"busy":  newChannelState(streamStart),
+				"quiet": newChannelState(streamStart),
+			},
+		},
+		Channels: []string{"busy", "quiet"},
+	}
+	rng := U.NewSeededRNG(5)
+	res := make(map[string]int)
+	for _, msg := range msgs {
+		if !serv.canSpawnAt(msg.cid, msg.Time) {
+			continue
+		}
+		if serv.G.Policy().Roll(rng, serv.G.Channel(msg.cid), msg.Message) {
+			res[msg.cid]++
+			serv.cooldownAt(rng, msg.cid, msg.Time)
+		}
+	}
+	return res
+}
+
+func TestSpawnPolicies(t *testing.T) {
+	a := assert.New(t)
+	lively := chat("busy", 600, time.Minute, "alice", "bob", "carol") // 10 hours
+	lonely := chat("busy", 600, time.Minute, "alice")                 // 10 hours
+	sparse := chat("busy", 120, 2*time.Hour, "alice", "bob", "carol") // 10 days
+
+	t.Run("rate", func(t *testing.T) {
+		a.Greater(play(PolicyRate, lively, 5*time.Minute)["busy"], 10)
+		// The authorless messages of a new history count as other authors until they are replaced
+		a.LessOrEqual(play(PolicyRate, lonely, 5*time.Minute)["busy"], 1)
+		a.Zero(play(PolicyRate, sparse, 5*time.Minute)["busy"])
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		// A visitor with the first message after each cooldown: every 6 minutes
+		a.Equal(100, play(PolicyInterval, lively, 5*time.Minute)["busy"])
+		a.Equal(100, play(PolicyInterval, lonely, 5*time.Minute)["busy"])
+		a.Equal(120, play(PolicyInterval, sparse, 5*time.Minute)["busy"])
+	})
+
+	t.Run("poisson", func(t *testing.T) {
+		// A visitor every cooldown + PoissonInterval in average
+		a.InDelta(40, play(PolicyPoisson, lively, 5*time.Minute)["busy"], 10)
+		a.Zero(play(PolicyPoisson, lonely, 5*time.Minute)["busy"])
+		a.Zero(play(PolicyPoisson, sparse, 5*time.Minute)["busy"])
+	})
+
+	t.Run("activity", func(t *testing.T) {
+		a.Greater(play(PolicyActivity, lively, 5*time.Minute)["busy"], play(PolicyActivity, lonely, 5*time.Minute)["busy"])
+		a.Less(play(PolicyActivity, sparse, 5*time.Minute)["busy"], 5)
+		// Each channel is scored on its own activity
+		visitors := play(PolicyActivity, merge(lively, chat("quiet", 20, 30*time.Minute, "dave", "erin")), 0)
+		a.Greater(visitors["busy"], 10*visitors["quiet"])
+	})
+
+	t.Run("command", func(t *testing.T) {
+		b := newTestBot(t, newFakeSession(0))
+		a.Equal(DefaultSpawnPolicy, b.GetServer("guild").G.PolicyName())
+		HandlerFromMessageCreate(b, b.command("setpolicy"))(nil, messageCreate("guild", "channel", "admin", "b!setpolicy poisson"))
+		a.Equal(PolicyPoisson, b.GetServer("guild").G.PolicyName())
+		HandlerFromMessageCreate(b, b.command("setpolicy"))(nil, messageCreate("guild", "channel", "admin", "b!setpolicy lorem"))
+		a.Equal(PolicyPoisson, b.GetServer("guild").G.PolicyName())
+	})
+}
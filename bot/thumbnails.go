@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ThumbnailTimeout bounds the download of a monster image.
+const ThumbnailTimeout = 5 * time.Second
+
+// thumbnailCache downloads monster images in the background, to draw them in rendered boards
+// without delaying interaction responses.
+type thumbnailCache struct {
+	mutex    sync.Mutex
+	images   map[string]image.Image
+	fetching map[string]bool
+	fetch    func(url string) (image.Image, error)
+}
+
+func newThumbnailCache(fetch func(url string) (image.Image, error)) *thumbnailCache {
+	return &thumbnailCache{
+		images:   make(map[string]image.Image),
+		fetching: make(map[string]bool),
+		fetch:    fetch,
+	}
+}
+
+func httpThumbnail(url string) (image.Image, error) {
+	client := http.Client{Timeout: ThumbnailTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	img, _, err := image.Decode(resp.Body)
+	return img, err
+}
+
+// Get returns the image at url, or nil if it is not downloaded yet, in which case the download
+// starts in the background. Failed downloads are retried on the next call.
+func (tc *thumbnailCache) Get(url string) image.Image {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	if img, ok := tc.images[url]; ok {
+		return img
+	}
+	if !tc.fetching[url] {
+		tc.fetching[url] = true
+		go tc.download(url)
+	}
+	return nil
+}
+
+func (tc *thumbnailCache) download(url string) {
+	img, err := tc.fetch(url)
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	delete(tc.fetching, url)
+	if err == nil {
+		tc.images[url] = img
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ashyaa/birtho/bot"
+	"github.com/sirupsen/logrus"
+)
+
+// command is an offline command working on the database while the bot is stopped.
+type command struct {
+	description string
+	run         func(logger *logrus.Logger, conf bot.Config, args []string) error
+}
+
+var commands = map[string]command{
+	"backup":       {"save a snapshot of the database and remove old snapshots", backupCommand},
+	"restore":      {"replace the database with a snapshot", restoreCommand},
+	"export":       {"export the game data of a server as JSON or CSV", exportCommand},
+	"import":       {"import the players' items of an export into a server", importCommand},
+	"guilds":       {"list the servers", guildsCommand},
+	"dump":         {"print the stored record of a server", dumpCommand},
+	"set":          {"change a game setting of servers", setCommand},
+	"grant":        {"give an item to a player", itemCommand("grant", (*bot.Bot).GrantItem)},
+	"revoke":       {"take an item back from a player", itemCommand("revoke", (*bot.Bot).RemoveItem)},
+	"recompute":    {"rebuild the leaderboards of servers from the players' items", recomputeCommand},
+	"clear-spawns": {"remove the visitors stuck in servers", clearSpawnsCommand},
+	"simulate":     {"estimate the game length and drop rates with simulated games", simulateCommand},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: birtho [command] [flags]")
+	fmt.Fprintln(os.Stderr, "Without command, runs the bot. Commands:")
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", name, commands[name].description)
+	}
+}
+
+// runCommand runs an offline command and returns the exit code.
+func runCommand(logger *logrus.Logger, name string, args []string) int {
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		return 2
+	}
+	logger.SetOutput(os.Stderr) // keep the standard output for the command output
+	conf, err := bot.ReadConfig(logger)
+	if err != nil {
+		logger.Error("error reading config: ", err)
+		return 1
+	}
+	err = cmd.run(logger, conf, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		logger.Error(err)
+		return 1
+	}
+	return 0
+}
+
+// output returns the file at path, or the standard output if path is empty.
+func output(path string) (io.WriteCloser, error) {
+	if path == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(path)
+}
+
+func exportCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	guild := flags.String("guild", "", "ID of the server to export")
+	format := flags.String("format", bot.FormatJSON, "file format: json or csv")
+	db := flags.String("db", bot.DefaultDBPath, "path of the database")
+	out := flags.String("out", "", "output file, the standard output if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *guild == "" {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+
+	b, err := bot.Open(logger, conf, *db, true)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	data, err := b.ExportGuild(*guild, *format)
+	if err != nil {
+		return err
+	}
+	w, err := output(*out)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, err = w.Write(data)
+	return err
+}
+
+func importCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := flags.String("file", "", "JSON or CSV file made by the export command")
+	guild := flags.String("guild", "", "ID of the server to import into, the exported server if empty")
+	mode := flags.String("mode", bot.ImportMerge, "merge with or replace the current items: merge or replace")
+	db := flags.String("db", bot.DefaultDBPath, "path of the database")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	b, err := bot.Open(logger, conf, *db, false)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	report, err := b.ImportGuild(*guild, data, *mode)
+	if err != nil {
+		return err
+	}
+	fmt.Println(report)
+	return nil
+}
+
+func backupCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	db := flags.String("db", bot.DefaultDBPath, "path of the database")
+	flags.StringVar(&conf.Backup.Dir, "dir", conf.Backup.Dir, "directory of the snapshots")
+	flags.IntVar(&conf.Backup.Keep, "keep", conf.Backup.Keep, "number of snapshots kept")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	b, err := bot.Open(logger, conf, *db, true)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	path, err := b.Backup()
+	if err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}
+
+func restoreCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	file := flags.String("file", "", "snapshot to restore, the latest one of the backup directory if empty")
+	db := flags.String("db", bot.DefaultDBPath, "path of the database")
+	dir := flags.String("dir", conf.Backup.Dir, "directory of the snapshots")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		if *dir == "" {
+			*dir = bot.DefaultBackupDir
+		}
+		paths, _, err := bot.Backups(*dir)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no snapshot in %s", *dir)
+		}
+		*file = paths[0]
+	}
+	if err := bot.RestoreBackup(*file, *db); err != nil {
+		return err
+	}
+	logger.Infof("restored %s to %s", *file, *db)
+	return nil
+}
+
+func simulateCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	sim := bot.DefaultSimulation()
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	flags.IntVar(&sim.Runs, "runs", sim.Runs, "number of simulated games")
+	flags.DurationVar(&sim.Duration, "duration", sim.Duration, "simulated time of each game")
+	flags.IntVar(&sim.Players, "players", sim.Players, "number of active players")
+	flags.Float64Var(&sim.MessagesPerHour, "messages-per-hour", sim.MessagesPerHour, "messages sent in the spawn channel per hour")
+	flags.DurationVar(&sim.Reaction, "reaction", sim.Reaction, "average time a player takes to greet a visitor")
+	flags.Float64Var(&sim.Attention, "attention", sim.Attention, "share of the players watching when a visitor comes")
+	flags.DurationVar(&sim.MinDelay, "min-cooldown", sim.MinDelay, "minimum cooldown between two visitors")
+	flags.DurationVar(&sim.MaxDelay, "max-cooldown", sim.MaxDelay, "maximum cooldown between two visitors")
+	flags.DurationVar(&sim.StayTime, "stay", sim.StayTime, "time a visitor waits for a greeting")
+	flags.StringVar(&sim.Policy, "policy", sim.Policy, "spawn policy: rate, interval, poisson or activity")
+	flags.Int64Var(&sim.Seed, "seed", sim.Seed, "seed of the random numbers, random if not set")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := bot.Simulate(logger, conf, sim)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+	return nil
+}
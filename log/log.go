@@ -1,6 +1,7 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path"
@@ -11,28 +12,114 @@ import (
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func New() LR.Logger {
-	res := *LR.New()
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	DefaultLevel      = "info"
+	DefaultMaxSize    = 500 // megabytes
+	DefaultMaxBackups = 3
+	DefaultMaxAge     = 28 // days
+)
+
+// Options configures the log output. Zero values fall back to the defaults.
+type Options struct {
+	Format     string `json:"format,omitempty" yaml:"format,omitempty"` // "text" or "json"
+	Level      string `json:"level,omitempty" yaml:"level,omitempty"`
+	Dir        string `json:"dir,omitempty" yaml:"dir,omitempty"` // defaults to "logs" beside the executable
+	MaxSize    int    `json:"max-size,omitempty" yaml:"max-size,omitempty"`
+	MaxBackups int    `json:"max-backups,omitempty" yaml:"max-backups,omitempty"`
+	MaxAge     int    `json:"max-age,omitempty" yaml:"max-age,omitempty"`
+	Compress   bool   `json:"compress,omitempty" yaml:"compress,omitempty"`
+}
+
+// New returns a logger writing colored text to the standard output only, until Configure is called.
+func New() *LR.Logger {
+	res := LR.New()
 	res.SetOutput(colorable.NewColorableStdout())
 	res.SetFormatter(&LR.TextFormatter{ForceColors: true, FullTimestamp: true})
-	exePath, err := os.Executable()
+	return res
+}
+
+// fileHook writes the log entries to a file with its own formatter, so that the file does not get
+// the colors of the standard output.
+type fileHook struct {
+	w io.Writer
+	f LR.Formatter
+}
+
+func (h fileHook) Levels() []LR.Level {
+	return LR.AllLevels
+}
+
+func (h fileHook) Fire(e *LR.Entry) error {
+	line, err := h.f.Format(e)
+	if err != nil {
+		return err
+	}
+	_, err = h.w.Write(line)
+	return err
+}
+
+// Configure sets the format, level and rotated log file of the logger. Text logs are only colored
+// on the standard output.
+func Configure(l *LR.Logger, opts Options) error {
+	var fileFormatter LR.Formatter
+	switch opts.Format {
+	case "", FormatText:
+		l.SetFormatter(&LR.TextFormatter{ForceColors: true, FullTimestamp: true})
+		fileFormatter = &LR.TextFormatter{DisableColors: true, FullTimestamp: true}
+	case FormatJSON:
+		l.SetFormatter(&LR.JSONFormatter{})
+		fileFormatter = &LR.JSONFormatter{}
+	default:
+		return fmt.Errorf("unknown log format %s", opts.Format)
+	}
+
+	if opts.Level == "" {
+		opts.Level = DefaultLevel
+	}
+	level, err := LR.ParseLevel(opts.Level)
 	if err != nil {
-		res.Fatal(err.Error())
+		return err
+	}
+	l.SetLevel(level)
+
+	logDir := opts.Dir
+	if logDir == "" {
+		exePath, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		logDir = FP.Join(path.Dir(exePath), "logs")
 	}
-	logDir := FP.Join(path.Dir(exePath), "logs")
 	if stat, err := os.Stat(logDir); err != nil {
-		if os.IsNotExist(err) {
-			os.Mkdir(logDir, 0755)
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if err = os.MkdirAll(logDir, 0755); err != nil {
+			return err
 		}
 	} else if !stat.IsDir() {
-		res.Fatalf("%s exists but is not a directory", logDir)
+		return fmt.Errorf("%s exists but is not a directory", logDir)
+	}
+
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
 	}
-	mw := io.MultiWriter(&lumberjack.Logger{
+	if opts.MaxBackups <= 0 {
+		opts.MaxBackups = DefaultMaxBackups
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	l.AddHook(fileHook{w: &lumberjack.Logger{
 		Filename:   FP.Join(logDir, "bot.log"),
-		MaxSize:    500, // megabytes
-		MaxBackups: 3,
-		MaxAge:     28, //days
-	}, colorable.NewColorableStdout())
-	res.SetOutput(mw)
-	return res
+		MaxSize:    opts.MaxSize,
+		MaxBackups: opts.MaxBackups,
+		MaxAge:     opts.MaxAge,
+		Compress:   opts.Compress,
+	}, f: fileFormatter})
+	l.SetOutput(colorable.NewColorableStdout())
+	return nil
 }
@@ -10,21 +10,31 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	logger logrus.Logger
-	b      *bot.Bot
-)
+func main() {
+	logger := log.New()
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(logger, os.Args[1], os.Args[2:]))
+	}
+	serve(logger)
+}
 
-func init() {
-	var err error
-	logger = log.New()
-	b, err = bot.New(&logger)
+// serve runs the bot until CTRL-C or other term signal is received.
+func serve(logger *logrus.Logger) {
+	conf, err := bot.ReadConfig(logger)
 	if err != nil {
+		logger.Error("error reading config: ", err)
 		panic(err)
 	}
-}
+	if err = log.Configure(logger, conf.Log); err != nil {
+		logger.Error("error configuring logs: ", err)
+		panic(err)
+	}
+	b, err := bot.New(logger, conf)
+	if err != nil {
+		panic(err)
+	}
+	b.ServeHealth()
 
-func main() {
 	// Wait here until CTRL-C or other term signal is received.
 	logger.Info("Bot is now running. Press CTRL-C to exit.")
 	sc := make(chan os.Signal, 1)
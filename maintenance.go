@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/ashyaa/birtho/bot"
+	"github.com/sirupsen/logrus"
+)
+
+// Offline maintenance commands, inspecting and editing the database while the bot is stopped.
+
+// serverFlags parses the flags common to the commands working on servers, and opens the database.
+type serverFlags struct {
+	*flag.FlagSet
+	db    *string
+	guild *string
+	all   *bool
+}
+
+func newServerFlags(name string, all bool) serverFlags {
+	res := serverFlags{FlagSet: flag.NewFlagSet(name, flag.ContinueOnError)}
+	res.db = res.String("db", bot.DefaultDBPath, "path of the database")
+	res.guild = res.String("guild", "", "ID of the server")
+	if all {
+		res.all = res.Bool("all", false, "apply to all the servers")
+	} else {
+		res.all = new(bool)
+	}
+	return res
+}
+
+// parse parses the arguments, returning an error if no server is selected.
+func (f serverFlags) parse(args []string, needsServer bool) error {
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+	if needsServer && *f.guild == "" && !*f.all {
+		f.Usage()
+		return flag.ErrHelp
+	}
+	return nil
+}
+
+// update applies change to the selected servers and saves them.
+func (f serverFlags) update(logger *logrus.Logger, conf bot.Config, change func(b *bot.Bot, serv bot.Server) (bot.Server, error)) error {
+	b, err := bot.Open(logger, conf, *f.db, false)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	servers := []bot.Server{}
+	if *f.all {
+		if servers, err = b.Servers(); err != nil {
+			return err
+		}
+	} else {
+		serv, err := b.FindServer(*f.guild)
+		if err != nil {
+			return fmt.Errorf("server %s: %w", *f.guild, err)
+		}
+		servers = append(servers, serv)
+	}
+	for _, serv := range servers {
+		serv, err = change(b, serv)
+		if err != nil {
+			return fmt.Errorf("server %s: %w", serv.ID, err)
+		}
+		b.SaveServer(serv)
+	}
+	return nil
+}
+
+func guildsCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	flags := newServerFlags("guilds", false)
+	if err := flags.parse(args, false); err != nil {
+		return err
+	}
+	b, err := bot.Open(logger, conf, *flags.db, true)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	servers, err := b.Servers()
+	if err != nil {
+		return err
+	}
+	for _, serv := range servers {
+		fmt.Println(b.ServerSummary(serv))
+	}
+	return nil
+}
+
+func dumpCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	flags := newServerFlags("dump", false)
+	if err := flags.parse(args, true); err != nil {
+		return err
+	}
+	b, err := bot.Open(logger, conf, *flags.db, true)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	serv, err := b.FindServer(*flags.guild)
+	if err != nil {
+		return fmt.Errorf("server %s: %w", *flags.guild, err)
+	}
+	data, err := json.MarshalIndent(serv, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func setCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	flags := newServerFlags("set", true)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: birtho set --guild <id>|--all <setting> <value>")
+		fmt.Fprintln(flags.Output(), "settings: prefix, play (on/off), min-cooldown, max-cooldown, stay (durations, eg 2m), global (on/off), policy (rate, interval, poisson or activity)")
+		flags.PrintDefaults()
+	}
+	if err := flags.parse(args, true); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	key, value := flags.Arg(0), flags.Arg(1)
+	return flags.update(logger, conf, func(_ *bot.Bot, serv bot.Server) (bot.Server, error) {
+		return bot.SetSetting(serv, key, value)
+	})
+}
+
+func itemCommand(name string, change func(b *bot.Bot, serv bot.Server, uid, item string) (bot.Server, error)) func(*logrus.Logger, bot.Config, []string) error {
+	return func(logger *logrus.Logger, conf bot.Config, args []string) error {
+		flags := newServerFlags(name, false)
+		user := flags.String("user", "", "ID of the player")
+		item := flags.String("item", "", "ID of the item, eg m1i2 for the second item of the monster 1")
+		if err := flags.parse(args, true); err != nil {
+			return err
+		}
+		if *user == "" || *item == "" {
+			flags.Usage()
+			return flag.ErrHelp
+		}
+		return flags.update(logger, conf, func(b *bot.Bot, serv bot.Server) (bot.Server, error) {
+			return change(b, serv, *user, *item)
+		})
+	}
+}
+
+func recomputeCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	flags := newServerFlags("recompute", true)
+	if err := flags.parse(args, true); err != nil {
+		return err
+	}
+	return flags.update(logger, conf, func(b *bot.Bot, serv bot.Server) (bot.Server, error) {
+		serv = b.RecomputeLeaderboard(serv)
+		logger.Infof("server %s: leaderboard of %d players recomputed", serv.ID, len(serv.Lb))
+		return serv, nil
+	})
+}
+
+func clearSpawnsCommand(logger *logrus.Logger, conf bot.Config, args []string) error {
+	flags := newServerFlags("clear-spawns", true)
+	if err := flags.parse(args, true); err != nil {
+		return err
+	}
+	return flags.update(logger, conf, func(_ *bot.Bot, serv bot.Server) (bot.Server, error) {
+		serv, count := bot.ClearSpawns(serv)
+		if count > 0 {
+			logger.Infof("server %s: %d visitors removed", serv.ID, count)
+		}
+		return serv, nil
+	})
+}
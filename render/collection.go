@@ -0,0 +1,74 @@
+package render
+
+import (
+	"image"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	cellWidth   = 128
+	cellHeight  = 168
+	thumbSize   = 96
+	itemSize    = 24
+	gridColumns = 5
+	gridMargin  = 16
+)
+
+type CollectionItem struct {
+	Owned  bool
+	Rarity Rarity
+}
+
+type CollectionMonster struct {
+	Name      string
+	Thumbnail image.Image // nil if unavailable
+	Items     []CollectionItem
+}
+
+// Collection draws the grid of monsters with the items a player owns, highlighting the monster at
+// index selected, if any.
+func Collection(heading string, monsters []CollectionMonster, selected int) (image.Image, error) {
+	rows := (len(monsters) + gridColumns - 1) / gridColumns
+	columns := min(len(monsters), gridColumns)
+	width := max(gridMargin*2+columns*cellWidth, 400)
+	height := gridMargin*2 + 32 + rows*cellHeight
+	c, unlock, err := newCanvas(width, height)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	c.text(title, heading, gridMargin, gridMargin+22, width-2*gridMargin, Text)
+	for i, monster := range monsters {
+		x := gridMargin + (i%gridColumns)*cellWidth
+		y := gridMargin + 32 + (i/gridColumns)*cellHeight
+		cell := image.Rect(x+4, y+4, x+cellWidth-4, y+cellHeight-4)
+		c.rect(cell, RowShade)
+		if i == selected {
+			c.outline(cell, 2, Text)
+		}
+
+		thumb := image.Rect(x+(cellWidth-thumbSize)/2, y+8, x+(cellWidth+thumbSize)/2, y+8+thumbSize)
+		if monster.Thumbnail != nil {
+			xdraw.CatmullRom.Scale(c, thumb, monster.Thumbnail, monster.Thumbnail.Bounds(), xdraw.Over, nil)
+		} else {
+			c.outline(thumb, 1, Muted)
+		}
+		c.text(regular, monster.Name, x+8, y+thumbSize+30, cellWidth-16, Text)
+
+		itemsWidth := len(monster.Items)*(itemSize+6) - 6
+		ix := x + (cellWidth-itemsWidth)/2
+		iy := y + thumbSize + 38
+		for _, item := range monster.Items {
+			square := image.Rect(ix, iy, ix+itemSize, iy+itemSize)
+			if item.Owned {
+				c.rect(square, RarityColors[item.Rarity])
+			} else {
+				c.outline(square, 2, RarityColors[item.Rarity])
+			}
+			ix += itemSize + 6
+		}
+	}
+	return c.RGBA, nil
+}
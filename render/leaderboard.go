@@ -0,0 +1,62 @@
+package render
+
+import (
+	"fmt"
+	"image"
+)
+
+const (
+	lbWidth     = 600
+	lbRowHeight = 32
+	lbMargin    = 16
+)
+
+type LeaderboardRow struct {
+	Rank     string
+	Position int // 1-based position in the whole leaderboard, for podium colors
+	Name     string
+	Score    int
+	Value    string // shown instead of the score if set
+}
+
+// Leaderboard draws a page of a leaderboard, whose scores are shown in the given column.
+func Leaderboard(heading, column string, rows []LeaderboardRow) (image.Image, error) {
+	height := lbMargin*2 + lbRowHeight*(len(rows)+2)
+	c, unlock, err := newCanvas(lbWidth, height)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	y := lbMargin
+	c.text(title, heading, lbMargin, y+24, lbWidth-2*lbMargin, Text)
+	y += lbRowHeight
+	c.text(bold, "Rank", lbMargin, y+22, 0, Muted)
+	c.text(bold, "User", lbMargin+80, y+22, 0, Muted)
+	c.textRight(bold, column, lbWidth-lbMargin, y+22, Muted)
+	y += lbRowHeight
+
+	for i, row := range rows {
+		if i%2 == 0 {
+			c.rect(image.Rect(lbMargin/2, y, lbWidth-lbMargin/2, y+lbRowHeight), RowShade)
+		}
+		col := Text
+		switch row.Position {
+		case 1:
+			col = Gold
+		case 2:
+			col = Silver
+		case 3:
+			col = Bronze
+		}
+		c.text(bold, row.Rank, lbMargin, y+22, 70, col)
+		c.text(regular, row.Name, lbMargin+80, y+22, lbWidth-2*lbMargin-80-90, col)
+		value := row.Value
+		if value == "" {
+			value = fmt.Sprintf("%d", row.Score)
+		}
+		c.textRight(bold, value, lbWidth-lbMargin, y+22, col)
+		y += lbRowHeight
+	}
+	return c.RGBA, nil
+}
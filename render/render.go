@@ -0,0 +1,164 @@
+// Package render draws the game boards as images, with the bundled Go fonts.
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+type Rarity int
+
+const (
+	Common Rarity = iota
+	Uncommon
+	Rare
+)
+
+func (r Rarity) String() string {
+	switch r {
+	case Uncommon:
+		return "uncommon"
+	case Rare:
+		return "rare"
+	}
+	return "common"
+}
+
+var (
+	Background = color.RGBA{0x2b, 0x2d, 0x31, 0xff}
+	RowShade   = color.RGBA{0x31, 0x33, 0x38, 0xff}
+	Text       = color.RGBA{0xdb, 0xde, 0xe1, 0xff}
+	Muted      = color.RGBA{0x80, 0x84, 0x8e, 0xff}
+	Gold       = color.RGBA{0xf1, 0xc4, 0x0f, 0xff}
+	Silver     = color.RGBA{0xbd, 0xc3, 0xc7, 0xff}
+	Bronze     = color.RGBA{0xcd, 0x7f, 0x32, 0xff}
+
+	RarityColors = map[Rarity]color.RGBA{
+		Common:   {0xf5, 0xb0, 0x41, 0xff},
+		Uncommon: {0xf3, 0x8b, 0x1c, 0xff},
+		Rare:     {0xe6, 0x5c, 0x00, 0xff},
+	}
+)
+
+var (
+	facesOnce sync.Once
+	faces     map[string]font.Face
+	facesErr  error
+	// font.Face values are not safe for concurrent use
+	facesMutex sync.Mutex
+)
+
+const (
+	regular = "regular"
+	bold    = "bold"
+	title   = "title"
+)
+
+func loadFaces() (map[string]font.Face, error) {
+	facesOnce.Do(func() {
+		regularFont, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			facesErr = err
+			return
+		}
+		boldFont, err := opentype.Parse(gobold.TTF)
+		if err != nil {
+			facesErr = err
+			return
+		}
+		faces = make(map[string]font.Face)
+		for name, def := range map[string]struct {
+			f    *opentype.Font
+			size float64
+		}{
+			regular: {regularFont, 16},
+			bold:    {boldFont, 16},
+			title:   {boldFont, 22},
+		} {
+			face, err := opentype.NewFace(def.f, &opentype.FaceOptions{Size: def.size, DPI: 72, Hinting: font.HintingFull})
+			if err != nil {
+				facesErr = err
+				return
+			}
+			faces[name] = face
+		}
+	})
+	return faces, facesErr
+}
+
+// canvas is an image being drawn, with the fonts locked for its whole drawing.
+type canvas struct {
+	*image.RGBA
+	faces map[string]font.Face
+}
+
+func newCanvas(width, height int) (*canvas, func(), error) {
+	f, err := loadFaces()
+	if err != nil {
+		return nil, nil, err
+	}
+	facesMutex.Lock()
+	res := &canvas{image.NewRGBA(image.Rect(0, 0, width, height)), f}
+	draw.Draw(res, res.Bounds(), image.NewUniform(Background), image.Point{}, draw.Src)
+	return res, facesMutex.Unlock, nil
+}
+
+func (c *canvas) rect(r image.Rectangle, col color.Color) {
+	draw.Draw(c, r, image.NewUniform(col), image.Point{}, draw.Over)
+}
+
+// outline draws the border of a rectangle, of the given thickness.
+func (c *canvas) outline(r image.Rectangle, thickness int, col color.Color) {
+	c.rect(image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+thickness), col)
+	c.rect(image.Rect(r.Min.X, r.Max.Y-thickness, r.Max.X, r.Max.Y), col)
+	c.rect(image.Rect(r.Min.X, r.Min.Y, r.Min.X+thickness, r.Max.Y), col)
+	c.rect(image.Rect(r.Max.X-thickness, r.Min.Y, r.Max.X, r.Max.Y), col)
+}
+
+// text draws s with its baseline starting at (x, y), truncated with an ellipsis to fit in width
+// pixels if width is positive.
+func (c *canvas) text(face string, s string, x, y, width int, col color.Color) {
+	d := font.Drawer{Dst: c, Src: image.NewUniform(col), Face: c.faces[face]}
+	s = truncate(d, s, width)
+	d.Dot = fixed.P(x, y)
+	d.DrawString(s)
+}
+
+// textRight draws s with its baseline ending at (x, y).
+func (c *canvas) textRight(face string, s string, x, y int, col color.Color) {
+	d := font.Drawer{Dst: c, Src: image.NewUniform(col), Face: c.faces[face]}
+	d.Dot = fixed.P(x-d.MeasureString(s).Ceil(), y)
+	d.DrawString(s)
+}
+
+func truncate(d font.Drawer, s string, width int) string {
+	if width <= 0 || d.MeasureString(s).Ceil() <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		res := string(runes) + "…"
+		if d.MeasureString(res).Ceil() <= width {
+			return res
+		}
+	}
+	return ""
+}
+
+// PNG encodes an image in the PNG format.
+func PNG(img image.Image) (*bytes.Buffer, error) {
+	res := new(bytes.Buffer)
+	err := png.Encode(res, img)
+	return res, err
+}
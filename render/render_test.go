@@ -0,0 +1,60 @@
+package render
+
+import (
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font"
+)
+
+func TestLeaderboard(t *testing.T) {
+	a := assert.New(t)
+	rows := []LeaderboardRow{
+		{Rank: "1st", Position: 1, Name: "lorem", Score: 230},
+		{Rank: "2nd", Position: 2, Name: "a very long name that will not fit in the name column at all", Score: 123},
+		{Rank: "2nd", Position: 3, Name: "ipsum", Score: 123},
+		{Rank: "4th", Position: 4, Name: "dolor", Value: "1.25s"},
+	}
+	img, err := Leaderboard("Server leaderboard", "Points", rows)
+	a.NoError(err)
+	a.Equal(lbWidth, img.Bounds().Dx())
+	a.Equal(lbMargin*2+lbRowHeight*6, img.Bounds().Dy())
+
+	buf, err := PNG(img)
+	a.NoError(err)
+	decoded, err := png.Decode(buf)
+	a.NoError(err)
+	a.Equal(img.Bounds(), decoded.Bounds())
+}
+
+func TestCollection(t *testing.T) {
+	a := assert.New(t)
+	monsters := []CollectionMonster{}
+	for i := 0; i < 7; i++ {
+		monsters = append(monsters, CollectionMonster{
+			Name:      "Ghost",
+			Thumbnail: image.NewRGBA(image.Rect(0, 0, 200, 200)),
+			Items:     []CollectionItem{{true, Common}, {false, Uncommon}, {i%2 == 0, Rare}},
+		})
+	}
+	monsters[3].Thumbnail = nil
+	img, err := Collection("lorem's collection", monsters, 2)
+	a.NoError(err)
+	a.Equal(gridMargin*2+gridColumns*cellWidth, img.Bounds().Dx())
+	a.Equal(gridMargin*2+32+2*cellHeight, img.Bounds().Dy())
+}
+
+func TestTruncate(t *testing.T) {
+	a := assert.New(t)
+	f, err := loadFaces()
+	a.NoError(err)
+	d := font.Drawer{Face: f[regular]}
+	res := truncate(d, "a name far too long to fit", 60)
+	a.True(strings.HasSuffix(res, "…"))
+	a.LessOrEqual(d.MeasureString(res).Ceil(), 60)
+	a.Equal("short", truncate(d, "short", 100))
+	a.Equal("no limit at all", truncate(d, "no limit at all", 0))
+}
@@ -10,7 +10,7 @@ import (
 )
 
 var ChannelTagPattern = regexp.MustCompile("<#([0-9]{18})>")
-var UserTagPattern = regexp.MustCompile("<@!([0-9]{18})>")
+var UserTagPattern = regexp.MustCompile("<@!?([0-9]{17,20})>")
 
 // Return true and the channel ID if the input string matched the channel tag format
 func StripChannelTag(cid string) (string, bool) {
@@ -45,8 +45,16 @@ func Timestamp(t time.Time) string {
 	return fmt.Sprintf("<t:%d:R>", t.Unix())
 }
 
+type ChannelLister interface {
+	GuildChannels(guildID string, options ...DG.RequestOption) ([]*DG.Channel, error)
+}
+
+type MemberGetter interface {
+	GuildMember(guildID, userID string, options ...DG.RequestOption) (*DG.Member, error)
+}
+
 // Return true if cid is a valid channel in the guild identifed by gid
-func IsValidChannel(s *DG.Session, gid, cid string) bool {
+func IsValidChannel(s ChannelLister, gid, cid string) bool {
 	channels, err := s.GuildChannels(gid)
 	if err != nil {
 		return false
@@ -60,7 +68,7 @@ func IsValidChannel(s *DG.Session, gid, cid string) bool {
 }
 
 // Return true if the user uid is a member of the server gid
-func IsUserInServer(s *DG.Session, gid, uid string) bool {
+func IsUserInServer(s MemberGetter, gid, uid string) bool {
 	_, err := s.GuildMember(gid, uid)
 	return err == nil
 }
@@ -75,7 +83,8 @@ func MemberName(m *DG.Member) string {
 	return m.User.Username
 }
 
-// CreationTime returns the creation time of a Snowflake ID relative to the creation of Discord.
+// CreationTime returns the creation time of a Snowflake ID relative to the creation of Discord,
+// with a millisecond precision.
 // Taken from https://github.com/Moonlington/FloSelfbot/blob/master/commands/commandutils.go#L117
 func CreationTime(ID string) (t time.Time, err error) {
 	i, err := strconv.ParseInt(ID, 10, 64)
@@ -83,6 +92,6 @@ func CreationTime(ID string) (t time.Time, err error) {
 		return
 	}
 	timestamp := (i >> 22) + 1420070400000
-	t = time.Unix(timestamp/1000, 0)
+	t = time.UnixMilli(timestamp)
 	return
 }
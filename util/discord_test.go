@@ -40,6 +40,11 @@ func TestStripUserTag(t *testing.T) {
 		a.True(ok)
 		a.Equal("951792639001366558", res)
 	})
+	t.Run("without nickname", func(t *testing.T) {
+		res, ok := StripUserTag("<@1195316420731117668>")
+		a.True(ok)
+		a.Equal("1195316420731117668", res)
+	})
 }
 
 func TestBuildUserTag(t *testing.T) {
@@ -49,3 +54,16 @@ func TestBuildUserTag(t *testing.T) {
 		a.Equal("<@!951792639001366558>", res)
 	})
 }
+
+func TestCreationTime(t *testing.T) {
+	a := assert.New(t)
+	t.Run("invalid", func(t *testing.T) {
+		_, err := CreationTime("lorem")
+		a.Error(err)
+	})
+	t.Run("nominal", func(t *testing.T) {
+		res, err := CreationTime("175928847299117063")
+		a.NoError(err)
+		a.Equal(int64(1462015105796), res.UnixMilli())
+	})
+}
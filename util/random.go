@@ -2,6 +2,7 @@ package util
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -10,24 +11,69 @@ const (
 	hundred int = 100 * factor
 )
 
-type RNG struct {
-	*rand.Rand
+// RNG is a Random Number Generator. Implementations must be safe for concurrent use, as the
+// game rolls from several goroutines.
+type RNG interface {
+	// Intn returns a random number in [0,n). It panics if n <= 0.
+	Intn(n int) int
+	// Float64 returns a random number in [0.0,1.0).
+	Float64() float64
+	// ExpFloat64 returns an exponentially distributed number of mean 1.
+	ExpFloat64() float64
+	// NormFloat64 returns a normally distributed number of mean 0 and standard deviation 1.
+	NormFloat64() float64
 }
 
-// NewRNG returns a new Random Number Generator
+// lockedRNG is a math/rand generator guarded by a mutex, since rand.Rand is not safe for
+// concurrent use
+type lockedRNG struct {
+	r     *rand.Rand
+	mutex *sync.Mutex
+}
+
+// NewRNG returns a new Random Number Generator seeded with the current time
 func NewRNG() RNG {
-	return RNG{rand.New(rand.NewSource(time.Now().UTC().UnixNano()))}
+	return NewSeededRNG(time.Now().UTC().UnixNano())
+}
+
+// NewSeededRNG returns a new Random Number Generator giving the same numbers for the same seed
+func NewSeededRNG(seed int64) RNG {
+	return lockedRNG{rand.New(rand.NewSource(seed)), &sync.Mutex{}}
+}
+
+func (r lockedRNG) Intn(n int) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.r.Intn(n)
+}
+
+func (r lockedRNG) Float64() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.r.Float64()
+}
+
+func (r lockedRNG) ExpFloat64() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.r.ExpFloat64()
+}
+
+func (r lockedRNG) NormFloat64() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.r.NormFloat64()
 }
 
-// PercentChance generates a random number generator, and returns true if it is strictly inferior
-// to 100. In other terms, it randomizes a `Rate%` probability.
-func (r RNG) PercentChance(rate int) bool {
+// PercentChance generates a random number with rng, and returns true if it is strictly inferior
+// to rate%. In other terms, it randomizes a `Rate%` probability.
+func PercentChance(rng RNG, rate int) bool {
 	if rate < 0 {
 		return false
 	}
 	if rate >= 100 {
 		return true
 	}
-	n := r.Intn(hundred)
+	n := rng.Intn(hundred)
 	return n < rate*factor
 }
@@ -1,6 +1,7 @@
 package util_test
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/ashyaa/birtho/util"
@@ -17,3 +18,44 @@ func TestProbability(t *testing.T) {
 		a.True(n < 5)
 	}
 }
+
+func TestSeededRNG(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("same seed", func(t *testing.T) {
+		first, second := util.NewSeededRNG(42), util.NewSeededRNG(42)
+		for i := 0; i < 100; i++ {
+			a.Equal(first.Intn(1000), second.Intn(1000))
+			a.Equal(first.Float64(), second.Float64())
+		}
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		rng := util.NewSeededRNG(42)
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					rng.Intn(10)
+					util.PercentChance(rng, 50)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("percent chance", func(t *testing.T) {
+		rng := util.NewSeededRNG(42)
+		a.False(util.PercentChance(rng, -1))
+		a.True(util.PercentChance(rng, 100))
+		hits := 0
+		for i := 0; i < 10000; i++ {
+			if util.PercentChance(rng, 30) {
+				hits++
+			}
+		}
+		a.InDelta(3000, hits, 300)
+	})
+}